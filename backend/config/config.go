@@ -1,6 +1,11 @@
 package config
 
-import "os"
+import (
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
 
 // JWTSecret is the secret key for JWT signing
 // In production, this should be loaded from environment variables
@@ -41,3 +46,112 @@ func NovaCanvasConfig() BedrockConfig {
 		ContentType:      "application/json",
 	}
 }
+
+
+// BattleStorageConfig contains battle persistence configuration
+type BattleStorageConfig struct {
+	Backend             string        // "memory" or "dynamodb"
+	TableName           string        // DynamoDB table for the dynamodb backend
+	TTL                 time.Duration // How long a battle is kept after its last update
+	HistoryTableName    string        // DynamoDB table for finished battle summaries
+	RatingTableName     string        // DynamoDB table for ladder ratings
+	TournamentTableName string        // DynamoDB table for tournaments
+}
+
+// LoadBattleStorageConfig reads battle storage settings from the environment
+func LoadBattleStorageConfig() BattleStorageConfig {
+	ttl, err := time.ParseDuration(GetEnvOrDefault("BATTLE_TTL", "1h"))
+	if err != nil {
+		log.Printf("Invalid BATTLE_TTL, using 1h: %v", err)
+		ttl = time.Hour
+	}
+
+	return BattleStorageConfig{
+		Backend:             GetEnvOrDefault("BATTLE_STORAGE", "memory"),
+		TableName:           GetEnvOrDefault("BATTLE_TABLE_NAME", "pokemon-battles"),
+		TTL:                 ttl,
+		HistoryTableName:    GetEnvOrDefault("BATTLE_HISTORY_TABLE_NAME", "pokemon-battle-history"),
+		RatingTableName:     GetEnvOrDefault("RATING_TABLE_NAME", "pokemon-ratings"),
+		TournamentTableName: GetEnvOrDefault("TOURNAMENT_TABLE_NAME", "pokemon-tournaments"),
+	}
+}
+
+// LadderConfig contains Elo ladder settings
+type LadderConfig struct {
+	KFactor             int  // Maximum rating change from a single battle
+	RateComputerBattles bool // Also rate battles against the computer, on a ladder per difficulty
+}
+
+// LoadLadderConfig reads ladder settings from the environment
+func LoadLadderConfig() LadderConfig {
+	kFactor, err := strconv.Atoi(GetEnvOrDefault("LADDER_K_FACTOR", "32"))
+	if err != nil || kFactor < 1 {
+		log.Printf("Invalid LADDER_K_FACTOR, using 32: %v", err)
+		kFactor = 32
+	}
+
+	return LadderConfig{
+		KFactor:             kFactor,
+		RateComputerBattles: GetEnvOrDefault("LADDER_RATE_COMPUTER", "false") == "true",
+	}
+}
+
+// BattleTimeoutConfig contains per-turn timeout settings
+type BattleTimeoutConfig struct {
+	TurnTimeout  time.Duration // How long a side has to choose each turn, 0 disables timeouts
+	Action       string        // "forfeit" or "auto-pick" for a side that runs out of time
+	MaxAutoPicks int           // Turns in a row a side can have picked for it before it forfeits
+}
+
+// LoadBattleTimeoutConfig reads turn timeout settings from the environment
+func LoadBattleTimeoutConfig() BattleTimeoutConfig {
+	timeout, err := time.ParseDuration(GetEnvOrDefault("BATTLE_TURN_TIMEOUT", "0"))
+	if err != nil || timeout < 0 {
+		log.Printf("Invalid BATTLE_TURN_TIMEOUT, disabling turn timeouts: %v", err)
+		timeout = 0
+	}
+
+	action := GetEnvOrDefault("BATTLE_TIMEOUT_ACTION", "auto-pick")
+	if action != "forfeit" && action != "auto-pick" {
+		log.Printf("Invalid BATTLE_TIMEOUT_ACTION %q, using auto-pick", action)
+		action = "auto-pick"
+	}
+
+	maxAutoPicks, err := strconv.Atoi(GetEnvOrDefault("BATTLE_MAX_AUTO_PICKS", "3"))
+	if err != nil || maxAutoPicks < 0 {
+		log.Printf("Invalid BATTLE_MAX_AUTO_PICKS, using 3: %v", err)
+		maxAutoPicks = 3
+	}
+
+	return BattleTimeoutConfig{
+		TurnTimeout:  timeout,
+		Action:       action,
+		MaxAutoPicks: maxAutoPicks,
+	}
+}
+
+// SimulationConfig contains limits for headless battle simulations
+type SimulationConfig struct {
+	MaxBattles  int // Most battles a single request can simulate
+	Concurrency int // Battles simulated at the same time across all requests
+}
+
+// LoadSimulationConfig reads simulation limits from the environment
+func LoadSimulationConfig() SimulationConfig {
+	maxBattles, err := strconv.Atoi(GetEnvOrDefault("SIMULATION_MAX_BATTLES", "1000"))
+	if err != nil || maxBattles < 1 {
+		log.Printf("Invalid SIMULATION_MAX_BATTLES, using 1000: %v", err)
+		maxBattles = 1000
+	}
+
+	concurrency, err := strconv.Atoi(GetEnvOrDefault("SIMULATION_CONCURRENCY", "4"))
+	if err != nil || concurrency < 1 {
+		log.Printf("Invalid SIMULATION_CONCURRENCY, using 4: %v", err)
+		concurrency = 4
+	}
+
+	return SimulationConfig{
+		MaxBattles:  maxBattles,
+		Concurrency: concurrency,
+	}
+}
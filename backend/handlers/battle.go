@@ -2,66 +2,64 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"math"
-	"math/rand"
+	"math/rand/v2"
 	"net/http"
 	"strings"
-	"sync"
 	"time"
 
 	"backend/middleware"
 )
 
-// In-memory battle storage
-var (
-	battleStore = make(map[string]*BattleState)
-	battleMutex = &sync.RWMutex{}
+const (
+	STABMultiplier        = 1.5
+	CriticalHitRate       = 1.0 / 24
+	CriticalHitMultiplier = 1.5
 )
 
-// cleanupOldBattles removes battles older than 1 hour from memory
-func cleanupOldBattles() {
-	battleMutex.Lock()
-	defer battleMutex.Unlock()
-	
-	now := time.Now()
-	cutoff := now.Add(-1 * time.Hour)
-	
-	for battleId, battle := range battleStore {
-		createdAt, err := time.Parse(time.RFC3339, battle.CreatedAt)
-		if err != nil {
-			log.Printf("Error parsing battle creation time: %v", err)
-			continue
-		}
-		
-		if createdAt.Before(cutoff) {
-			delete(battleStore, battleId)
-			log.Printf("Cleaned up old battle: %s", battleId)
-		}
-	}
-}
-
-// Start cleanup routine (called once when server starts)
-func init() {
-	go func() {
-		ticker := time.NewTicker(30 * time.Minute) // Clean up every 30 minutes
-		defer ticker.Stop()
-		
-		for range ticker.C {
-			cleanupOldBattles()
-		}
-	}()
-}
+const (
+	BattleModePvE = "pve" // Player against the computer
+	BattleModePvP = "pvp" // Two users; the opponent controls the computer-side team
+)
 
 type BattleState struct {
 	BattleId       string    `json:"battleId"`
 	UserId         string    `json:"userId"`
-	PlayerPokemon  BattlePokemon `json:"playerPokemon"`
-	ComputerPokemon BattlePokemon `json:"computerPokemon"`
-	CurrentTurn    string    `json:"currentTurn"` // "player" or "computer"
-	BattleStatus   string    `json:"battleStatus"` // "active", "won", "lost"
+	Mode           string    `json:"mode"`                     // "pve" or "pvp"
+	OpponentUserId string    `json:"opponentUserId,omitempty"` // PvP only: the user who accepted the challenge
+	PlayerName     string    `json:"playerName,omitempty"`
+	OpponentName   string    `json:"opponentName,omitempty"`
+	PlayerTeam     []BattlePokemon `json:"playerTeam"`
+	ComputerTeam   []BattlePokemon `json:"computerTeam"`
+	PlayerActive   int       `json:"playerActive"`   // Index of the player's Pokemon in battle
+	ComputerActive int       `json:"computerActive"` // Index of the computer's Pokemon in battle
+	Field          FieldState `json:"field"`         // Weather and terrain, with the turns each has left
+	Difficulty     string    `json:"difficulty"`       // "easy", "normal" or "hard"
+	OpponentStrategy string  `json:"opponentStrategy"` // Strategy the computer uses, e.g. "greedy"
+	CurrentTurn    string    `json:"currentTurn"` // "player" (choosing actions), "switch" (replacing a fainted Pokemon) or "finished"
+	BattleStatus   string    `json:"battleStatus"` // "pending" (PvP challenge not yet accepted), "active", "won", "lost", "forfeited" or "timed-out"
+	EndReason      string    `json:"endReason,omitempty"`   // "knockout", "forfeit", "run" or "timeout"
+	ForfeitedBy    string    `json:"forfeitedBy,omitempty"` // Side that forfeited, ran or timed out
+	TurnDeadline   string    `json:"turnDeadline,omitempty"` // Sides that haven't chosen by then time out
+	TimeoutStrikes map[string]int `json:"timeoutStrikes,omitempty"` // Turns in a row each side had its choice auto-picked
+	WinnerUserId   string    `json:"winnerUserId,omitempty"`
+	PendingChoices map[string]BattleChoice `json:"pendingChoices,omitempty"` // PvP choices submitted this turn, keyed by side
+	ForcedSwitches []string  `json:"forcedSwitches,omitempty"`               // Sides that must send out a new Pokemon
+	Version        int       `json:"version"`                                // Incremented on every save
+	Seed           int64     `json:"seed,omitempty"`                         // Hidden until the battle is over
+	CustomSeed     bool      `json:"customSeed,omitempty"`                   // Seed was chosen by the player, so the battle isn't rated
+	RNG            *battleRNG `json:"rng,omitempty"`                         // Position in the seeded sequence, never sent to clients
+	InitialPlayerTeam   []BattlePokemon `json:"initialPlayerTeam,omitempty"`   // Teams as they were when the battle started, for replays
+	InitialComputerTeam []BattlePokemon `json:"initialComputerTeam,omitempty"`
+	Choices        []ReplayTurn `json:"choices,omitempty"` // Every side's choice for each resolved turn, for replays
+	SpectatorToken string    `json:"spectatorToken,omitempty"`               // Grants read-only access, only shown to the owner
+	StreamTokens   map[string]StreamToken `json:"streamTokens,omitempty"` // Short-lived tokens for streaming events without an Authorization header, keyed by side
+	LegalActions   []BattleChoice `json:"legalActions,omitempty"`          // Choices the viewer can submit right now, only set on responses
+	TournamentId   string    `json:"tournamentId,omitempty"`                 // Set for tournament matches, which advance the bracket when they end
 	CreatedAt      string    `json:"createdAt"`
 	UpdatedAt      string    `json:"updatedAt"`
 	TurnHistory    []TurnAction `json:"turnHistory"`
@@ -75,36 +73,85 @@ type BattlePokemon struct {
 	Types        []string `json:"types"`
 	SpriteUrl    string   `json:"spriteUrl"`
 	Moves        []PokemonMove `json:"moves"`
-	Stats        PokemonStats `json:"stats"`
+	Stats        PokemonStats `json:"stats"`     // Actual stats at the Pokemon's level
+	BaseStats    PokemonStats `json:"baseStats"` // Species base stats from PokeAPI
+	Level        int          `json:"level"`
+	IVs          PokemonStats `json:"ivs"`
+	EVs          PokemonStats `json:"evs"`
+	Nature       string       `json:"nature"`
+	Status       string   `json:"status,omitempty"` // "burn", "poison", "paralysis", "sleep" or "freeze"
+	SleepTurns   int      `json:"sleepTurns,omitempty"`
+	StatStages   StatStages `json:"statStages"`
+	Ability      string   `json:"ability,omitempty"`      // From the Pokemon's legal abilities on PokeAPI
+	Item         string   `json:"item,omitempty"`         // Held item, e.g. "leftovers"
+	ItemConsumed bool     `json:"itemConsumed,omitempty"` // Berries are used up once eaten
+	Flinched     bool     `json:"flinched,omitempty"`     // Loses its move this turn, cleared at the end of the turn
 }
 
 type PokemonMove struct {
-	Name     string `json:"name"`
-	Power    int    `json:"power"`
-	Type     string `json:"type"`
-	PP       int    `json:"pp"`
-	CurrentPP int   `json:"currentPp"`
+	Name          string `json:"name"`
+	Power         int    `json:"power"`
+	Type          string `json:"type"`
+	PP            int    `json:"pp"`
+	CurrentPP     int    `json:"currentPp"`
+	Accuracy      int    `json:"accuracy"`                // Percent chance to hit, 0 means the move never misses
+	DamageClass   string `json:"damageClass"`             // "physical", "special" or "status"
+	Ailment       string `json:"ailment,omitempty"`       // Status condition the move can inflict
+	AilmentChance int    `json:"ailmentChance,omitempty"` // Percent chance to inflict, 0 means always for status moves
+	StatChanges   []StatChange `json:"statChanges,omitempty"`
+	StatChance    int          `json:"statChance,omitempty"` // Percent chance to apply StatChanges, 0 means always
+	StatTarget    string       `json:"statTarget,omitempty"` // "user" or "target"
+	Priority      int          `json:"priority,omitempty"`   // Moves with higher priority go first, e.g. 1 for Quick Attack
+	Drain         int          `json:"drain,omitempty"`        // Percent of damage dealt the user recovers, negative for recoil
+	Healing       int          `json:"healing,omitempty"`      // Percent of max HP the user recovers, e.g. 50 for Recover
+	MinHits       int          `json:"minHits,omitempty"`      // Multi-hit moves strike MinHits to MaxHits times
+	MaxHits       int          `json:"maxHits,omitempty"`
+	FlinchChance  int          `json:"flinchChance,omitempty"` // Percent chance the target flinches
+	CritRate      int          `json:"critRate,omitempty"`     // Crit stage bonus, 1 for high crit-rate moves like Slash
 }
 
 type PokemonStats struct {
-	HP      int `json:"hp"`
-	Attack  int `json:"attack"`
-	Defense int `json:"defense"`
-	Speed   int `json:"speed"`
+	HP             int `json:"hp"`
+	Attack         int `json:"attack"`
+	Defense        int `json:"defense"`
+	SpecialAttack  int `json:"specialAttack"`
+	SpecialDefense int `json:"specialDefense"`
+	Speed          int `json:"speed"`
 }
 
 type TurnAction struct {
-	Turn      int    `json:"turn"`
-	Actor     string `json:"actor"` // "player" or "computer"
-	Action    string `json:"action"` // "attack"
-	MoveName  string `json:"moveName"`
-	Damage    int    `json:"damage"`
-	Message   string `json:"message"`
-	Timestamp string `json:"timestamp"`
+	Turn                 int     `json:"turn"`
+	Actor                string  `json:"actor"` // "player" or "computer"
+	Action               string  `json:"action"` // "attack", "switch", "faint", "status", "flinch", "ability", "item", "weather" or "terrain"
+	MoveName             string  `json:"moveName"`
+	Damage               int     `json:"damage"`
+	Effectiveness        float64 `json:"effectiveness"`                  // Type multiplier: 0, 0.25, 0.5, 1, 2 or 4
+	EffectivenessMessage string  `json:"effectivenessMessage,omitempty"` // "It's super effective!", etc.
+	Missed               bool    `json:"missed"`
+	CriticalHit          bool    `json:"criticalHit"`
+	STAB                 bool    `json:"stab"` // Same-type attack bonus applied
+	StatusInflicted      string  `json:"statusInflicted,omitempty"`
+	StatChanges          []StatChange `json:"statChanges,omitempty"`
+	Recoil               int     `json:"recoil,omitempty"` // Damage the attacker took from its own move
+	Hits                 int     `json:"hits,omitempty"`    // Times a multi-hit move struck
+	Drained              int     `json:"drained,omitempty"` // HP the attacker recovered from the damage it dealt
+	Healed               int     `json:"healed,omitempty"`  // HP recovered by a healing move
+	Message              string  `json:"message"`
+	Timestamp            string  `json:"timestamp"`
 }
 
 type StartBattleRequest struct {
-	PlayerPokemonId int `json:"playerPokemonId"`
+	PlayerPokemonId  int   `json:"playerPokemonId"`            // Single Pokemon battle, used when PlayerTeamIds is empty
+	PlayerTeamIds    []int `json:"playerTeamIds,omitempty"`    // 1-6 Pokemon IDs
+	ComputerTeamSize int   `json:"computerTeamSize,omitempty"` // Defaults to the player's team size
+	Level            int   `json:"level,omitempty"`            // Player team level, defaults to 50
+	ComputerLevel    int   `json:"computerLevel,omitempty"`    // Defaults to Level
+	PlayerSpreads    []PokemonSpread `json:"playerSpreads,omitempty"` // Optional per-member spreads, in team order
+	PlayerMovesets   [][]string      `json:"playerMovesets,omitempty"` // Optional per-member move names (up to 4), in team order
+	PlayerAbilities  []string        `json:"playerAbilities,omitempty"` // Optional per-member abilities, each one the Pokemon can legally have
+	PlayerItems      []string        `json:"playerItems,omitempty"`     // Optional per-member held items, in team order
+	Difficulty       string          `json:"difficulty,omitempty"`     // "easy" (default), "normal" or "hard"
+	Seed             *int64          `json:"seed,omitempty"`           // Random seed, picked at random if omitted
 }
 
 type StartBattleResponse struct {
@@ -113,18 +160,31 @@ type StartBattleResponse struct {
 }
 
 type MakeMoveRequest struct {
+	Action   string `json:"action"` // "attack" (default), "switch", "forfeit" or "run" (computer battles only)
 	MoveName string `json:"moveName"`
+	SwitchTo int    `json:"switchTo"` // Team index to switch to
+}
+
+// BattleChoice is the action one side picks for a turn
+type BattleChoice struct {
+	Action   string `json:"action"`
+	MoveName string `json:"moveName,omitempty"`
+	SwitchTo int    `json:"switchTo,omitempty"`
 }
 
 type MakeMoveResponse struct {
 	Battle    *BattleState `json:"battle,omitempty"`
 	TurnResult *TurnResult `json:"turnResult,omitempty"`
+	WaitingForOpponent bool `json:"waitingForOpponent,omitempty"` // PvP choice stored until the opponent picks theirs
 	Error     string       `json:"error,omitempty"`
 }
 
 type TurnResult struct {
 	PlayerAction   *TurnAction `json:"playerAction,omitempty"`
 	ComputerAction *TurnAction `json:"computerAction,omitempty"`
+	StatusEvents   []TurnAction `json:"statusEvents,omitempty"` // Wake-ups, thaws, residual damage, faints and forced switches
+	SwitchRequired bool        `json:"switchRequired,omitempty"` // A side must send out a new Pokemon before the next turn, see ForcedSwitches
+	TurnOrder      *TurnOrder  `json:"turnOrder,omitempty"`      // Who attacked first and why, not set for forced switches
 	BattleEnded    bool        `json:"battleEnded"`
 	Winner         string      `json:"winner,omitempty"` // "player", "computer", or empty if ongoing
 }
@@ -170,29 +230,90 @@ func StartBattleHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Validate player Pokemon ID
-	if req.PlayerPokemonId < 1 || req.PlayerPokemonId > 1000 {
+	// Single Pokemon requests are a team of one
+	playerTeamIds := req.PlayerTeamIds
+	if len(playerTeamIds) == 0 {
+		playerTeamIds = []int{req.PlayerPokemonId}
+	}
+
+	if err := validateTeamRequest(playerTeamIds, req.PlayerMovesets); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(StartBattleResponse{Error: err.Error()})
+		return
+	}
+	if err := validateLoadout(len(playerTeamIds), req.PlayerAbilities, req.PlayerItems); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(StartBattleResponse{Error: err.Error()})
+		return
+	}
+
+	computerTeamSize := req.ComputerTeamSize
+	if computerTeamSize == 0 {
+		computerTeamSize = len(playerTeamIds)
+	}
+	if computerTeamSize < 1 || computerTeamSize > MaxTeamSize {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(StartBattleResponse{Error: fmt.Sprintf("Computer team size must be between 1 and %d", MaxTeamSize)})
+		return
+	}
+
+	playerSpreads, err := buildSpreads(len(playerTeamIds), req.Level, req.PlayerSpreads)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(StartBattleResponse{Error: err.Error()})
+		return
+	}
+
+	computerLevel := req.ComputerLevel
+	if computerLevel == 0 {
+		computerLevel = req.Level
+	}
+	computerSpreads, err := buildSpreads(computerTeamSize, computerLevel, nil)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(StartBattleResponse{Error: fmt.Sprintf("Computer %s", err.Error())})
+		return
+	}
+
+	difficulty := req.Difficulty
+	if difficulty == "" {
+		difficulty = DefaultDifficulty
+	}
+	strategyName, ok := difficultyStrategies[difficulty]
+	if !ok {
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StartBattleResponse{Error: "Player Pokemon ID must be between 1 and 1000"})
+		json.NewEncoder(w).Encode(StartBattleResponse{Error: "Difficulty must be: easy, normal, or hard"})
 		return
 	}
 
-	log.Printf("User %s starting battle with Pokemon ID: %d", user.Username, req.PlayerPokemonId)
+	log.Printf("User %s starting battle with Pokemon IDs: %v", user.Username, playerTeamIds)
+
+	seed := newBattleSeed()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+	teamRNG := newTeamRNG(seed)
 
-	// Generate random computer Pokemon (1-1000)
-	rand.Seed(time.Now().UnixNano())
-	computerPokemonId := rand.Intn(1000) + 1
+	// Generate random computer team (1-1000)
+	computerTeamIds := make([]int, computerTeamSize)
+	for i := range computerTeamIds {
+		computerTeamIds[i] = teamRNG.IntN(1000) + 1
+	}
 
-	// Fetch both Pokemon data from PokeAPI
-	playerPokemon, err := fetchBattlePokemonData(req.PlayerPokemonId)
+	// Fetch both teams from PokeAPI
+	playerTeam, err := fetchBattleTeam(playerTeamIds, playerSpreads, req.PlayerMovesets, req.PlayerAbilities, req.PlayerItems)
 	if err != nil {
 		log.Printf("Error fetching player Pokemon data: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(StartBattleResponse{Error: "Failed to fetch player Pokemon data"})
+		if isLoadoutError(err) {
+			json.NewEncoder(w).Encode(StartBattleResponse{Error: err.Error()})
+		} else {
+			json.NewEncoder(w).Encode(StartBattleResponse{Error: "Failed to fetch player Pokemon data"})
+		}
 		return
 	}
 
-	computerPokemon, err := fetchBattlePokemonData(computerPokemonId)
+	computerTeam, err := fetchBattleTeam(computerTeamIds, computerSpreads, nil, nil, nil)
 	if err != nil {
 		log.Printf("Error fetching computer Pokemon data: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
@@ -202,31 +323,38 @@ func StartBattleHandler(w http.ResponseWriter, r *http.Request) {
 
 	// Create battle state
 	now := time.Now()
-	battleId := fmt.Sprintf("%s_%d", user.Sub, now.Unix())
-	
 	battle := &BattleState{
-		BattleId:        battleId,
-		UserId:          user.Sub,
-		PlayerPokemon:   *playerPokemon,
-		ComputerPokemon: *computerPokemon,
-		CurrentTurn:     "player", // Player always goes first
-		BattleStatus:    "active",
-		CreatedAt:       now.Format(time.RFC3339),
-		UpdatedAt:       now.Format(time.RFC3339),
-		TurnHistory:     []TurnAction{},
+		UserId:              user.Sub,
+		Mode:                BattleModePvE,
+		PlayerName:          user.Username,
+		PlayerTeam:          playerTeam,
+		ComputerTeam:        computerTeam,
+		Difficulty:          difficulty,
+		OpponentStrategy:    strategyName,
+		Seed:                seed,
+		CustomSeed:          req.Seed != nil,
+		InitialPlayerTeam:   cloneTeam(playerTeam),
+		InitialComputerTeam: cloneTeam(computerTeam),
+		CurrentTurn:         "player", // Player always goes first
+		BattleStatus:        "active",
+		CreatedAt:           now.Format(time.RFC3339),
+		UpdatedAt:           now.Format(time.RFC3339),
+		TurnHistory:         []TurnAction{},
 	}
+	applyLeadEffects(battle, now.Format(time.RFC3339))
+	battle.startTurnClock(now)
 
 	// Save battle state to DynamoDB
-	if err := saveBattleState(battle); err != nil {
+	if err := createBattle(battle, user.Sub, now); err != nil {
 		log.Printf("Error saving battle state: %v", err)
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(StartBattleResponse{Error: "Failed to save battle state"})
 		return
 	}
 
-	log.Printf("Successfully started battle: %s, Player: %s vs Computer: %s", battleId, playerPokemon.Name, computerPokemon.Name)
+	log.Printf("Successfully started battle: %s, Player: %s vs Computer: %s", battle.BattleId, teamNames(playerTeam), teamNames(computerTeam))
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(StartBattleResponse{Battle: battle})
+	json.NewEncoder(w).Encode(StartBattleResponse{Battle: battle.viewFor("player")})
 }
 
 func MakeMoveHandler(w http.ResponseWriter, r *http.Request) {
@@ -281,7 +409,17 @@ func MakeMoveHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.MoveName == "" {
+	if req.Action == "" {
+		req.Action = "attack"
+	}
+
+	if req.Action != "attack" && req.Action != "switch" && req.Action != "forfeit" && req.Action != "run" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(MakeMoveResponse{Error: "Unknown action " + req.Action})
+		return
+	}
+
+	if req.Action == "attack" && req.MoveName == "" {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(MakeMoveResponse{Error: "Move name required"})
 		return
@@ -304,14 +442,26 @@ func MakeMoveHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check if it's player's turn
-	if battle.CurrentTurn != "player" {
+	if battle.CurrentTurn != "player" && battle.CurrentTurn != "switch" {
 		w.WriteHeader(http.StatusBadRequest)
 		json.NewEncoder(w).Encode(MakeMoveResponse{Error: "It's not your turn"})
 		return
 	}
 
-	// Process the turn
-	turnResult, err := processBattleTurn(battle, req.MoveName)
+	// Process the turn; PvP turns wait until both users have chosen
+	hpBefore := teamHP(battle)
+	side := battle.sideOf(user.Sub)
+	choice := BattleChoice{
+		Action:   req.Action,
+		MoveName: req.MoveName,
+		SwitchTo: req.SwitchTo,
+	}
+	var turnResult *TurnResult
+	if battle.isPvP() {
+		turnResult, err = submitChoice(battle, side, choice)
+	} else {
+		turnResult, err = processBattleTurn(battle, choice)
+	}
 	if err != nil {
 		log.Printf("Error processing battle turn: %v", err)
 		w.WriteHeader(http.StatusBadRequest)
@@ -319,20 +469,49 @@ func MakeMoveHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Choosing in time clears any auto-picked turns, and a resolved turn restarts the clock
+	now := time.Now()
+	delete(battle.TimeoutStrikes, side)
+	if turnResult != nil {
+		battle.startTurnClock(now)
+	}
+
 	// Save updated battle state
-	battle.UpdatedAt = time.Now().Format(time.RFC3339)
+	battle.UpdatedAt = now.Format(time.RFC3339)
 	if err := saveBattleState(battle); err != nil {
 		log.Printf("Error saving updated battle state: %v", err)
+		if errors.Is(err, ErrBattleConflict) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(MakeMoveResponse{Error: "Battle was updated by another request, please try again"})
+			return
+		}
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(MakeMoveResponse{Error: "Failed to save battle state"})
 		return
 	}
 
+	if turnResult != nil && turnResult.BattleEnded {
+		onBattleFinished(battle)
+	}
+
+	// Push the outcome to anyone streaming the battle
+	if turnResult != nil {
+		battleEvents.publish(battleId, turnEvents(battle, hpBefore, turnResult)...)
+	} else {
+		battleEvents.publish(battleId, BattleEvent{
+			Type:      "choice",
+			BattleId:  battleId,
+			Side:      side,
+			Timestamp: battle.UpdatedAt,
+		})
+	}
+
 	log.Printf("Successfully processed move for battle: %s", battleId)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(MakeMoveResponse{
-		Battle:     battle,
-		TurnResult: turnResult,
+		Battle:             battle.viewFor(side),
+		TurnResult:         turnResult,
+		WaitingForOpponent: turnResult == nil,
 	})
 }
 
@@ -390,10 +569,10 @@ func GetBattleHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(GetBattleResponse{Battle: battle})
+	json.NewEncoder(w).Encode(GetBattleResponse{Battle: battle.viewFor(battle.sideOf(user.Sub))})
 }
 
-func fetchBattlePokemonData(pokemonId int) (*BattlePokemon, error) {
+func fetchBattlePokemonData(pokemonId int, level int, moveNames []string, abilityName string) (*BattlePokemon, error) {
 	// Create HTTP client with timeout
 	client := &http.Client{
 		Timeout: RequestTimeout,
@@ -465,6 +644,10 @@ func fetchBattlePokemonData(pokemonId int) (*BattlePokemon, error) {
 							stats.Attack = int(baseStat)
 						case "defense":
 							stats.Defense = int(baseStat)
+						case "special-attack":
+							stats.SpecialAttack = int(baseStat)
+						case "special-defense":
+							stats.SpecialDefense = int(baseStat)
 						case "speed":
 							stats.Speed = int(baseStat)
 						}
@@ -474,38 +657,42 @@ func fetchBattlePokemonData(pokemonId int) (*BattlePokemon, error) {
 		}
 	}
 
-	// Extract moves (limit to first 4 for battle)
-	var moves []PokemonMove
+	// Pick the moveset from the Pokemon's learnset
+	var learnset map[string]learnsetEntry
 	if movesData, ok := pokeData["moves"].([]interface{}); ok {
-		count := 0
-		for _, moveInfo := range movesData {
-			if count >= 4 {
-				break
-			}
-			if moveMap, ok := moveInfo.(map[string]interface{}); ok {
-				if moveData, ok := moveMap["move"].(map[string]interface{}); ok {
-					if moveName, ok := moveData["name"].(string); ok {
-						// Fetch move details for power and type
-						moveDetails := fetchMoveDetails(moveName)
-						moves = append(moves, moveDetails)
-						count++
-					}
-				}
-			}
-		}
+		learnset = parseLearnset(movesData)
+	}
+
+	moves, err := selectMoveset(name, learnset, moveNames, level, types)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pick the ability from the Pokemon's legal abilities
+	var abilities []pokemonAbility
+	if abilitiesData, ok := pokeData["abilities"].([]interface{}); ok {
+		abilities = parseAbilities(abilitiesData)
+	}
+
+	ability, err := selectAbility(name, abilities, abilityName)
+	if err != nil {
+		return nil, err
 	}
 
 	// Ensure we have at least one move
 	if len(moves) == 0 {
 		moves = append(moves, PokemonMove{
-			Name:      "tackle",
-			Power:     40,
-			Type:      "normal",
-			PP:        35,
-			CurrentPP: 35,
+			Name:        "tackle",
+			Power:       40,
+			Type:        "normal",
+			PP:          35,
+			CurrentPP:   35,
+			Accuracy:    100,
+			DamageClass: "physical",
 		})
 	}
 
+	// Actual stats are filled in by applySpread once the level and spread are known
 	battlePokemon := &BattlePokemon{
 		PokemonId: pokemonId,
 		Name:      name,
@@ -515,12 +702,18 @@ func fetchBattlePokemonData(pokemonId int) (*BattlePokemon, error) {
 		SpriteUrl: spriteUrl,
 		Moves:     moves,
 		Stats:     stats,
+		BaseStats: stats,
+		Ability:   ability,
 	}
 
 	return battlePokemon, nil
 }
 
 func fetchMoveDetails(moveName string) PokemonMove {
+	if move, ok := getCachedMove(moveName); ok {
+		return move
+	}
+
 	// Create HTTP client with timeout
 	client := &http.Client{
 		Timeout: RequestTimeout,
@@ -533,51 +726,27 @@ func fetchMoveDetails(moveName string) PokemonMove {
 	resp, err := client.Get(moveAPIURL)
 	if err != nil {
 		// Return default move if API call fails
-		return PokemonMove{
-			Name:      moveName,
-			Power:     40,
-			Type:      "normal",
-			PP:        20,
-			CurrentPP: 20,
-		}
+		return defaultMove(moveName)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		// Return default move if API call fails
-		return PokemonMove{
-			Name:      moveName,
-			Power:     40,
-			Type:      "normal",
-			PP:        20,
-			CurrentPP: 20,
-		}
+		return defaultMove(moveName)
 	}
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		// Return default move if parsing fails
-		return PokemonMove{
-			Name:      moveName,
-			Power:     40,
-			Type:      "normal",
-			PP:        20,
-			CurrentPP: 20,
-		}
+		return defaultMove(moveName)
 	}
 
 	// Parse move data
 	var moveData map[string]interface{}
 	if err := json.Unmarshal(body, &moveData); err != nil {
 		// Return default move if parsing fails
-		return PokemonMove{
-			Name:      moveName,
-			Power:     40,
-			Type:      "normal",
-			PP:        20,
-			CurrentPP: 20,
-		}
+		return defaultMove(moveName)
 	}
 
 	// Extract move details
@@ -595,6 +764,18 @@ func fetchMoveDetails(moveName string) PokemonMove {
 		}
 	}
 
+	accuracy := 0 // moves without an accuracy value never miss
+	if accuracyData, ok := moveData["accuracy"]; ok && accuracyData != nil {
+		if accuracyFloat, ok := accuracyData.(float64); ok {
+			accuracy = int(accuracyFloat)
+		}
+	}
+
+	var priority int
+	if priorityData, ok := moveData["priority"].(float64); ok {
+		priority = int(priorityData)
+	}
+
 	moveType := "normal" // default type
 	if typeData, ok := moveData["type"].(map[string]interface{}); ok {
 		if typeName, ok := typeData["name"].(string); ok {
@@ -602,159 +783,557 @@ func fetchMoveDetails(moveName string) PokemonMove {
 		}
 	}
 
+	// Extract secondary ailment, ignoring ones the battle engine doesn't model, and the
+	// rest of the meta block
+	var ailment string
+	var ailmentChance, statChance int
+	var drain, healing, minHits, maxHits, flinchChance, critRate int
+	if meta, ok := moveData["meta"].(map[string]interface{}); ok {
+		if ailmentData, ok := meta["ailment"].(map[string]interface{}); ok {
+			if ailmentName, ok := ailmentData["name"].(string); ok && isSupportedStatus(ailmentName) {
+				ailment = ailmentName
+			}
+		}
+		if chance, ok := meta["ailment_chance"].(float64); ok {
+			ailmentChance = int(chance)
+		}
+		if chance, ok := meta["stat_chance"].(float64); ok {
+			statChance = int(chance)
+		}
+		if value, ok := meta["drain"].(float64); ok {
+			drain = int(value)
+		}
+		if value, ok := meta["healing"].(float64); ok {
+			healing = int(value)
+		}
+		if value, ok := meta["min_hits"].(float64); ok {
+			minHits = int(value)
+		}
+		if value, ok := meta["max_hits"].(float64); ok {
+			maxHits = int(value)
+		}
+		if value, ok := meta["flinch_chance"].(float64); ok {
+			flinchChance = int(value)
+		}
+		if value, ok := meta["crit_rate"].(float64); ok {
+			critRate = int(value)
+		}
+	}
+
+	// Extract stat stage changes
+	var statChanges []StatChange
+	if changesData, ok := moveData["stat_changes"].([]interface{}); ok {
+		for _, changeInfo := range changesData {
+			if changeMap, ok := changeInfo.(map[string]interface{}); ok {
+				change, _ := changeMap["change"].(float64)
+				if statData, ok := changeMap["stat"].(map[string]interface{}); ok {
+					if statName, ok := statData["name"].(string); ok {
+						statChanges = append(statChanges, StatChange{Stat: statName, Change: int(change)})
+					}
+				}
+			}
+		}
+	}
+
+	var moveTarget string
+	if targetData, ok := moveData["target"].(map[string]interface{}); ok {
+		moveTarget, _ = targetData["name"].(string)
+	}
+
+	damageClass := "physical" // default damage class
+	if classData, ok := moveData["damage_class"].(map[string]interface{}); ok {
+		if className, ok := classData["name"].(string); ok {
+			damageClass = className
+		}
+	}
+
+	// Status moves have no power and must not fall back to the default
+	if damageClass == "status" {
+		power = 0
+	}
+
+	var statTarget string
+	if len(statChanges) > 0 {
+		statTarget = statChangeTarget(moveName, damageClass, moveTarget, statChanges)
+	}
+
+	move := PokemonMove{
+		Name:          moveName,
+		Power:         power,
+		Type:          moveType,
+		PP:            pp,
+		CurrentPP:     pp,
+		Accuracy:      accuracy,
+		DamageClass:   damageClass,
+		Ailment:       ailment,
+		AilmentChance: ailmentChance,
+		StatChanges:   statChanges,
+		StatChance:    statChance,
+		StatTarget:    statTarget,
+		Priority:      priority,
+		Drain:         drain,
+		Healing:       healing,
+		MinHits:       minHits,
+		MaxHits:       maxHits,
+		FlinchChance:  flinchChance,
+		CritRate:      critRate,
+	}
+	cacheMove(move)
+
+	return move
+}
+
+// defaultMove returns the fallback move used when PokeAPI move details are unavailable
+func defaultMove(moveName string) PokemonMove {
 	return PokemonMove{
-		Name:      moveName,
-		Power:     power,
-		Type:      moveType,
-		PP:        pp,
-		CurrentPP: pp,
+		Name:        moveName,
+		Power:       40,
+		Type:        "normal",
+		PP:          20,
+		CurrentPP:   20,
+		Accuracy:    100,
+		DamageClass: "physical",
 	}
 }
 
-func processBattleTurn(battle *BattleState, playerMoveName string) (*TurnResult, error) {
+// processBattleTurn plays the player's choice against the computer's strategy
+func processBattleTurn(battle *BattleState, playerChoice BattleChoice) (*TurnResult, error) {
+	if isForfeit(playerChoice) {
+		return forfeitBattle(battle, "player", playerChoice.Action), nil
+	}
+	if err := validateChoice(battle, "player", playerChoice); err != nil {
+		return nil, err
+	}
+
+	choices := map[string]BattleChoice{"player": playerChoice}
+	if battle.CurrentTurn != "switch" {
+		// Let the battle's opponent strategy pick the computer's move
+		choices["computer"] = strategyFor(battle).ChooseAction(battle, "computer")
+	}
+	return resolveTurn(battle, choices), nil
+}
+
+// validateChoice checks that a side can make the given choice right now
+func validateChoice(battle *BattleState, actor string, choice BattleChoice) error {
+	if battle.CurrentTurn == "switch" {
+		if !battle.mustSwitch(actor) {
+			return fmt.Errorf("waiting for your opponent to send out a new Pokemon")
+		}
+		if choice.Action != "switch" {
+			return fmt.Errorf("your Pokemon fainted, you must switch to another Pokemon")
+		}
+	}
+
+	switch choice.Action {
+	case "switch":
+		return validateSwitch(battle.team(actor), battle.activeIndex(actor), choice.SwitchTo)
+	case "attack":
+		return validateAttack(battle.activePokemon(actor), choice.MoveName)
+	default:
+		return fmt.Errorf("unknown action %s", choice.Action)
+	}
+}
+
+// resolveTurn plays out a turn once every side that needs to act has a validated choice
+func resolveTurn(battle *BattleState, choices map[string]BattleChoice) *TurnResult {
 	turnResult := &TurnResult{}
-	turnNumber := len(battle.TurnHistory) + 1
-
-	// Find the player's selected move
-	var playerMove *PokemonMove
-	for i, move := range battle.PlayerPokemon.Moves {
-		if strings.EqualFold(move.Name, playerMoveName) {
-			if move.CurrentPP <= 0 {
-				return nil, fmt.Errorf("move %s has no PP left", playerMoveName)
+	turnNumber := nextTurnNumber(battle)
+	now := time.Now().Format(time.RFC3339)
+	battle.Choices = append(battle.Choices, ReplayTurn{Turn: turnNumber, Choices: choices})
+
+	// A forced switch after a faint is resolved on its own without using up a turn
+	if battle.CurrentTurn == "switch" {
+		for _, actor := range []string{"player", "computer"} {
+			choice, ok := choices[actor]
+			if !ok {
+				continue
 			}
-			playerMove = &battle.PlayerPokemon.Moves[i]
-			break
+			action := switchPokemon(battle, actor, choice.SwitchTo, turnNumber-1, now)
+			battle.TurnHistory = append(battle.TurnHistory, *action)
+			turnResult.setAction(actor, action)
 		}
+		battle.ForcedSwitches = nil
+		battle.CurrentTurn = "player"
+		return turnResult
 	}
 
-	if playerMove == nil {
-		return nil, fmt.Errorf("move %s not found", playerMoveName)
+	// Switches happen before any attacks
+	for _, actor := range []string{"player", "computer"} {
+		if choices[actor].Action == "switch" {
+			action := switchPokemon(battle, actor, choices[actor].SwitchTo, turnNumber, now)
+			battle.TurnHistory = append(battle.TurnHistory, *action)
+			turnResult.setAction(actor, action)
+		}
 	}
 
-	// Select random move for computer
-	rand.Seed(time.Now().UnixNano())
-	computerMoveIndex := rand.Intn(len(battle.ComputerPokemon.Moves))
-	computerMove := &battle.ComputerPokemon.Moves[computerMoveIndex]
+	// Determine attack order from move priority, then speed
+	turnResult.TurnOrder = decideTurnOrder(battle, choices)
+	order := turnResult.TurnOrder.Order
 
-	// Determine turn order based on speed
-	playerGoesFirst := battle.PlayerPokemon.Stats.Speed >= battle.ComputerPokemon.Stats.Speed
+	for _, actor := range order {
+		if choices[actor].Action != "attack" {
+			continue
+		}
 
-	var firstMove, secondMove *PokemonMove
-	var firstAttacker, secondAttacker, firstTarget, secondTarget *BattlePokemon
-	var firstActor, secondActor string
+		attacker := battle.activePokemon(actor)
+		defender := battle.activePokemon(opponentOf(actor))
 
-	if playerGoesFirst {
-		firstMove = playerMove
-		secondMove = computerMove
-		firstAttacker = &battle.PlayerPokemon
-		secondAttacker = &battle.ComputerPokemon
-		firstTarget = &battle.ComputerPokemon
-		secondTarget = &battle.PlayerPokemon
-		firstActor = "player"
-		secondActor = "computer"
-	} else {
-		firstMove = computerMove
-		secondMove = playerMove
-		firstAttacker = &battle.ComputerPokemon
-		secondAttacker = &battle.PlayerPokemon
-		firstTarget = &battle.PlayerPokemon
-		secondTarget = &battle.ComputerPokemon
-		firstActor = "computer"
-		secondActor = "player"
+		// A Pokemon knocked out earlier in the turn doesn't get to move
+		if attacker.CurrentHP <= 0 || defender.CurrentHP <= 0 {
+			continue
+		}
+		move := moveFor(attacker, choices[actor].MoveName)
+		takeTurn(battle, turnResult, attacker, defender, move, actor, turnNumber, now)
+		if checkBattleEnd(battle, turnResult, actor) {
+			return turnResult
+		}
 	}
 
-	now := time.Now().Format(time.RFC3339)
+	// Flinches only last for the turn they happen in
+	for _, actor := range order {
+		battle.activePokemon(actor).Flinched = false
+	}
 
-	// First attack
-	damage1 := calculateDamage(firstAttacker, firstTarget, firstMove)
-	firstTarget.CurrentHP = int(math.Max(0, float64(firstTarget.CurrentHP-damage1)))
-	firstMove.CurrentPP--
+	// End-of-turn residual damage from burn and poison, in turn order
+	for _, actor := range order {
+		if tick := applyResidualDamage(battle.activePokemon(actor), actor, turnNumber, now); tick != nil {
+			recordStatusEvent(battle, turnResult, tick)
+		}
+		if checkBattleEnd(battle, turnResult, actor) {
+			return turnResult
+		}
+	}
 
-	firstAction := &TurnAction{
-		Turn:      turnNumber,
-		Actor:     firstActor,
-		Action:    "attack",
-		MoveName:  firstMove.Name,
-		Damage:    damage1,
-		Message:   fmt.Sprintf("%s used %s! It dealt %d damage!", firstAttacker.Name, firstMove.Name, damage1),
-		Timestamp: now,
+	// Weather damage, then end-of-turn abilities and held items, e.g. Leftovers
+	for _, actor := range order {
+		for _, action := range applyFieldEffects(battle, actor, turnNumber, now) {
+			recordStatusEvent(battle, turnResult, action)
+		}
+		for _, action := range endOfTurnEffects(battle, actor, turnNumber, now) {
+			recordStatusEvent(battle, turnResult, action)
+		}
+		if checkBattleEnd(battle, turnResult, actor) {
+			return turnResult
+		}
+	}
+	for _, action := range tickField(battle, turnNumber, now) {
+		recordStatusEvent(battle, turnResult, action)
 	}
 
-	battle.TurnHistory = append(battle.TurnHistory, *firstAction)
+	// Replace fainted Pokemon; users pick their own replacement, the computer sends out its next one
+	battle.CurrentTurn = "player"
+	for _, actor := range order {
+		if battle.activePokemon(actor).CurrentHP > 0 {
+			continue
+		}
+		recordStatusEvent(battle, turnResult, faintAction(battle.activePokemon(actor), actor, turnNumber, now))
+
+		if battle.controlledByUser(actor) {
+			battle.CurrentTurn = "switch"
+			battle.ForcedSwitches = append(battle.ForcedSwitches, actor)
+			turnResult.SwitchRequired = true
+		} else {
+			next := nextAvailablePokemon(battle.ComputerTeam)
+			recordStatusEvent(battle, turnResult, switchPokemon(battle, "computer", next, turnNumber, now))
+		}
+	}
 
-	if playerGoesFirst {
-		turnResult.PlayerAction = firstAction
+	return turnResult
+}
+
+// setAction records a side's main action for the turn
+func (t *TurnResult) setAction(actor string, action *TurnAction) {
+	if actor == "player" {
+		t.PlayerAction = action
 	} else {
-		turnResult.ComputerAction = firstAction
+		t.ComputerAction = action
 	}
+}
 
-	// Check if battle is over after first attack
-	if firstTarget.CurrentHP <= 0 {
-		if firstActor == "player" {
-			battle.BattleStatus = "won"
-			turnResult.Winner = "player"
-		} else {
-			battle.BattleStatus = "lost"
-			turnResult.Winner = "computer"
+// findMove returns the Pokemon's move with the given name, or nil
+func findMove(pokemon *BattlePokemon, moveName string) *PokemonMove {
+	for i, move := range pokemon.Moves {
+		if strings.EqualFold(move.Name, moveName) {
+			return &pokemon.Moves[i]
 		}
-		turnResult.BattleEnded = true
-		battle.CurrentTurn = "finished"
-		return turnResult, nil
 	}
+	return nil
+}
+
+// nextTurnNumber returns the number of the turn about to be played
+func nextTurnNumber(battle *BattleState) int {
+	if len(battle.TurnHistory) == 0 {
+		return 1
+	}
+	return battle.TurnHistory[len(battle.TurnHistory)-1].Turn + 1
+}
 
-	// Second attack (if first didn't end the battle)
-	damage2 := calculateDamage(secondAttacker, secondTarget, secondMove)
-	secondTarget.CurrentHP = int(math.Max(0, float64(secondTarget.CurrentHP-damage2)))
-	secondMove.CurrentPP--
+// takeTurn lets one side act, honouring any status condition that prevents it from moving
+func takeTurn(battle *BattleState, turnResult *TurnResult, attacker *BattlePokemon, defender *BattlePokemon, move *PokemonMove, actor string, turnNumber int, timestamp string) {
+	statusAction, canMove := checkStatusBeforeMove(battle.random().Rand, attacker, actor, turnNumber, timestamp)
+
+	var action *TurnAction
+	switch {
+	case !canMove:
+		action = statusAction
+	case attacker.Flinched:
+		if statusAction != nil {
+			recordStatusEvent(battle, turnResult, statusAction)
+		}
+		action = flinchAction(attacker, actor, turnNumber, timestamp)
+	default:
+		if statusAction != nil {
+			recordStatusEvent(battle, turnResult, statusAction)
+		}
+		action = executeMove(battle.random().Rand, &battle.Field, attacker, defender, move, actor, turnNumber, timestamp)
+	}
 
-	secondAction := &TurnAction{
+	battle.TurnHistory = append(battle.TurnHistory, *action)
+	turnResult.setAction(actor, action)
+}
+
+// recordStatusEvent adds a status tick, faint or forced switch to both the battle history and the turn result
+func recordStatusEvent(battle *BattleState, turnResult *TurnResult, action *TurnAction) {
+	battle.TurnHistory = append(battle.TurnHistory, *action)
+	turnResult.StatusEvents = append(turnResult.StatusEvents, *action)
+}
+
+// checkBattleEnd marks the battle as finished once either side's whole team has
+// fainted. It runs after every action and every end-of-turn effect, so the first
+// team to go down loses. If one action knocks out both last Pokemon, e.g. a recoil
+// move or Rocky Helmet, the side that acted wins, whichever side that is.
+func checkBattleEnd(battle *BattleState, turnResult *TurnResult, actor string) bool {
+	playerDefeated, computerDefeated := teamDefeated(battle.PlayerTeam), teamDefeated(battle.ComputerTeam)
+	switch {
+	case playerDefeated && computerDefeated:
+		turnResult.Winner = actor
+	case playerDefeated:
+		turnResult.Winner = "computer"
+	case computerDefeated:
+		turnResult.Winner = "player"
+	default:
+		return false
+	}
+
+	if turnResult.Winner == "player" {
+		battle.BattleStatus = "won"
+		battle.WinnerUserId = battle.UserId
+	} else {
+		battle.BattleStatus = "lost"
+		battle.WinnerUserId = battle.OpponentUserId
+	}
+	turnResult.BattleEnded = true
+	battle.EndReason = "knockout"
+	battle.CurrentTurn = "finished"
+	battle.TurnDeadline = ""
+	return true
+}
+
+// executeMove applies a single move from attacker to defender and returns the resulting action
+func executeMove(rng *rand.Rand, field *FieldState, attacker *BattlePokemon, defender *BattlePokemon, move *PokemonMove, actor string, turnNumber int, timestamp string) *TurnAction {
+	// Struggle has no PP to use up, and PP never goes below zero
+	if move.CurrentPP > 0 {
+		move.CurrentPP--
+	}
+
+	action := &TurnAction{
 		Turn:      turnNumber,
-		Actor:     secondActor,
+		Actor:     actor,
 		Action:    "attack",
-		MoveName:  secondMove.Name,
-		Damage:    damage2,
-		Message:   fmt.Sprintf("%s used %s! It dealt %d damage!", secondAttacker.Name, secondMove.Name, damage2),
-		Timestamp: now,
+		MoveName:  move.Name,
+		Timestamp: timestamp,
+	}
+
+	// Roll for accuracy before anything else
+	if !moveHits(rng, attacker, defender, move) {
+		action.Missed = true
+		action.Message = fmt.Sprintf("%s used %s! But it missed!", attacker.Name, move.Name)
+		return action
 	}
 
-	battle.TurnHistory = append(battle.TurnHistory, *secondAction)
+	// Status moves never deal damage
+	if move.DamageClass == "status" {
+		action.Effectiveness = 1
+		action.Message = fmt.Sprintf("%s used %s!", attacker.Name, move.Name)
 
-	if playerGoesFirst {
-		turnResult.ComputerAction = secondAction
-	} else {
-		turnResult.PlayerAction = secondAction
+		// Weather and terrain moves like Rain Dance
+		if message, ok := field.applyMove(move.Name); ok {
+			if message == "" {
+				message = "But it failed!"
+			}
+			action.Message += " " + message
+			return action
+		}
+
+		if move.Ailment == "" && len(move.StatChanges) == 0 && move.Healing == 0 {
+			action.Message += " But nothing happened!"
+			return action
+		}
+
+		if statusMoveImmune(move, defender) {
+			action.Effectiveness = 0
+			action.Message += fmt.Sprintf(" It doesn't affect %s...", defender.Name)
+			return action
+		}
+
+		succeeded := false
+		if move.Healing > 0 && applyHealing(attacker, move, action) {
+			succeeded = true
+		}
+		if move.Ailment != "" && rollAilment(rng, move) && inflictStatus(rng, defender, move.Ailment) {
+			action.StatusInflicted = move.Ailment
+			action.Message += " " + statusInflictedMessage(defender)
+			succeeded = true
+		}
+		if rollStatChanges(rng, move) {
+			messages := applyMoveStatChanges(attacker, defender, move, action)
+			for _, message := range messages {
+				action.Message += " " + message
+			}
+			succeeded = succeeded || len(action.StatChanges) > 0
+		}
+		if !succeeded {
+			action.Message += " But it failed!"
+		}
+		return action
 	}
 
-	// Check if battle is over after second attack
-	if secondTarget.CurrentHP <= 0 {
-		if secondActor == "player" {
-			battle.BattleStatus = "won"
-			turnResult.Winner = "player"
-		} else {
-			battle.BattleStatus = "lost"
-			turnResult.Winner = "computer"
+	_, effectMessages := damageModifier(attacker, defender, move)
+	result, hits, hitMessages := strike(rng, field, attacker, defender, move)
+
+	action.Damage = result.Damage
+	action.Effectiveness = result.Effectiveness
+	action.EffectivenessMessage = effectivenessMessage(result.Effectiveness)
+	action.CriticalHit = result.CriticalHit
+	action.STAB = result.STAB
+
+	action.Message = fmt.Sprintf("%s used %s! It dealt %d damage!", attacker.Name, move.Name, result.Damage)
+	if isStruggle(move.Name) {
+		action.Message = fmt.Sprintf("%s has no moves left! ", attacker.Name) + action.Message
+	}
+	if move.MaxHits > 1 {
+		action.Hits = hits
+		action.Message += fmt.Sprintf(" It hit %d time(s)!", hits)
+	}
+	if result.CriticalHit {
+		action.Message += " A critical hit!"
+	}
+	if action.EffectivenessMessage != "" {
+		action.Message += " " + action.EffectivenessMessage
+	}
+	for _, message := range append(effectMessages, hitMessages...) {
+		action.Message += " " + message
+	}
+	if isStruggle(move.Name) {
+		applyStruggleRecoil(attacker, action)
+	}
+	applyDrain(attacker, defender, move, result.Damage, action)
+	for _, message := range afterDamageEffects(rng, attacker, defender, move, result.Damage, true) {
+		action.Message += " " + message
+	}
+	if result.Effectiveness == 0 {
+		return action
+	}
+
+	rollFlinch(rng, defender, move)
+
+	// Secondary ailment chance, only if the target is still standing
+	if defender.CurrentHP > 0 && rollAilment(rng, move) && inflictStatus(rng, defender, move.Ailment) {
+		action.StatusInflicted = move.Ailment
+		action.Message += " " + statusInflictedMessage(defender)
+	}
+
+	// Secondary stat changes, skipped when the target has fainted
+	if (move.StatTarget == "user" || defender.CurrentHP > 0) && rollStatChanges(rng, move) {
+		for _, message := range applyMoveStatChanges(attacker, defender, move, action) {
+			action.Message += " " + message
 		}
-		turnResult.BattleEnded = true
-		battle.CurrentTurn = "finished"
-	} else {
-		// Battle continues, it's still player's turn for next round
-		battle.CurrentTurn = "player"
 	}
 
-	return turnResult, nil
+	return action
 }
 
-func calculateDamage(attacker *BattlePokemon, defender *BattlePokemon, move *PokemonMove) int {
-	// Simple damage calculation formula
-	// Damage = ((2 * Level + 10) / 250) * (Attack / Defense) * Power + 2
-	// Using simplified formula for demo: (Attack * Power) / (Defense * 2) with some randomness
-	
-	level := 50 // Assume level 50 for all Pokemon
-	baseDamage := float64((2*level+10)*attacker.Stats.Attack*move.Power) / float64(250*defender.Stats.Defense)
-	
+// applyMoveStatChanges applies a move's stat changes to the user or target and records them on the action
+func applyMoveStatChanges(attacker *BattlePokemon, defender *BattlePokemon, move *PokemonMove, action *TurnAction) []string {
+	affected := defender
+	if move.StatTarget == "user" {
+		affected = attacker
+	}
+
+	applied, messages := applyStatChanges(affected, move.StatChanges)
+	action.StatChanges = append(action.StatChanges, applied...)
+	return messages
+}
+
+// moveHits rolls the move's accuracy check, including accuracy and evasion stages
+func moveHits(rng *rand.Rand, attacker *BattlePokemon, defender *BattlePokemon, move *PokemonMove) bool {
+	if move.Accuracy <= 0 {
+		return true
+	}
+
+	chance := float64(move.Accuracy) * accuracyStageMultiplier(attacker.StatStages.Accuracy-defender.StatStages.Evasion)
+	if chance >= 100 {
+		return true
+	}
+	return rng.Float64()*100 < chance
+}
+
+// hasType reports whether the Pokemon has the given type
+func hasType(pokemon *BattlePokemon, pokemonType string) bool {
+	for _, t := range pokemon.Types {
+		if strings.EqualFold(t, pokemonType) {
+			return true
+		}
+	}
+	return false
+}
+
+// damageStats returns the attacking and defending stat used by a move's damage class,
+// including stat stages. Critical hits ignore the attacker's drops and the defender's boosts.
+func damageStats(attacker *BattlePokemon, defender *BattlePokemon, move *PokemonMove, criticalHit bool) (int, int) {
+	attack, defense := attacker.Stats.Attack, defender.Stats.Defense
+	attackStage, defenseStage := attacker.StatStages.Attack, defender.StatStages.Defense
+	if move.DamageClass == "special" {
+		attack, defense = attacker.Stats.SpecialAttack, defender.Stats.SpecialDefense
+		attackStage, defenseStage = attacker.StatStages.SpecialAttack, defender.StatStages.SpecialDefense
+	}
+
+	if criticalHit {
+		attackStage = int(math.Max(0, float64(attackStage)))
+		defenseStage = int(math.Min(0, float64(defenseStage)))
+	}
+	attack = int(float64(attack) * statStageMultiplier(attackStage))
+	defense = int(float64(defense) * statStageMultiplier(defenseStage))
+
+	// Guard against missing stats from PokeAPI
+	if attack < 1 {
+		attack = 1
+	}
+	if defense < 1 {
+		defense = 1
+	}
+	return attack, defense
+}
+
+// damageResult describes the outcome of a single damage calculation
+type damageResult struct {
+	Damage        int
+	Effectiveness float64
+	CriticalHit   bool
+	STAB          bool
+}
+
+func calculateDamage(rng *rand.Rand, field *FieldState, attacker *BattlePokemon, defender *BattlePokemon, move *PokemonMove) damageResult {
+	// Critical hits are rolled first since they change which stat stages apply
+	criticalHit := rng.Float64() < criticalHitChance(move)
+
+	baseDamage, effectiveness, stab := baseDamage(field, attacker, defender, move, criticalHit)
+	if effectiveness == 0 {
+		return damageResult{Damage: 0, Effectiveness: 0}
+	}
+
 	// Add some randomness (85-100% of base damage)
-	rand.Seed(time.Now().UnixNano())
-	randomFactor := 0.85 + rand.Float64()*0.15
+	randomFactor := 0.85 + rng.Float64()*0.15
 	damage := int(baseDamage * randomFactor)
 	
 	// Ensure minimum damage of 1
@@ -762,32 +1341,92 @@ func calculateDamage(attacker *BattlePokemon, defender *BattlePokemon, move *Pok
 		damage = 1
 	}
 	
-	return damage
+	return damageResult{
+		Damage:        damage,
+		Effectiveness: effectiveness,
+		CriticalHit:   criticalHit,
+		STAB:          stab,
+	}
+}
+
+// baseDamage applies every damage modifier except the random factor, returning the
+// damage along with the type effectiveness and whether STAB applied
+func baseDamage(field *FieldState, attacker *BattlePokemon, defender *BattlePokemon, move *PokemonMove, criticalHit bool) (float64, float64, bool) {
+	// Simple damage calculation formula
+	// Damage = ((2 * Level + 10) / 250) * (Attack / Defense) * Power + 2
+	level := attacker.Level
+	if level == 0 {
+		level = DefaultBattleLevel
+	}
+
+	attack, defense := damageStats(attacker, defender, move, criticalHit)
+	damage := float64((2*level+10)*attack*move.Power)/float64(250*defense) + 2
+
+	// Apply type effectiveness against all of the defender's types
+	effectiveness := typeEffectiveness(move.Type, defender.Types)
+	if effectiveness == 0 {
+		return 0, 0, false
+	}
+	damage *= effectiveness
+
+	// Abilities and held items, e.g. Levitate or Life Orb
+	modifier, _ := damageModifier(attacker, defender, move)
+	if modifier == 0 {
+		return 0, 0, false
+	}
+	damage *= modifier
+
+	// Weather and terrain, e.g. rain boosting Water moves
+	damage *= field.damageModifier(attacker, defender, move)
+
+	// Same-type attack bonus
+	stab := hasType(attacker, move.Type)
+	if stab {
+		damage *= STABMultiplier
+	}
+
+	// Burned Pokemon deal half damage with physical moves
+	if attacker.Status == StatusBurn && move.DamageClass != "special" {
+		damage *= 0.5
+	}
+
+	// Critical hits
+	if criticalHit {
+		damage *= CriticalHitMultiplier
+	}
+
+	return damage, effectiveness, stab
+}
+
+// onBattleFinished records a saved, finished battle in the players' history and ratings,
+// and advances its tournament bracket.
+// Failures are logged since the battle itself has already been saved.
+func onBattleFinished(battle *BattleState) {
+	recordBattleHistory(battle)
+	if err := updateRatings(battle); err != nil {
+		log.Printf("Error updating ratings for %s: %v", battle.BattleId, err)
+	}
+	if battle.TournamentId != "" {
+		if err := recordTournamentResult(battle); err != nil {
+			log.Printf("Error advancing tournament %s: %v", battle.TournamentId, err)
+		}
+	}
 }
 
 func saveBattleState(battle *BattleState) error {
-	battleMutex.Lock()
-	defer battleMutex.Unlock()
-	
-	// Store battle in memory using battleId as key
-	battleStore[battle.BattleId] = battle
-	return nil
+	return getBattleStore().Save(battle)
 }
 
 func loadBattleState(battleId, userId string) (*BattleState, error) {
-	battleMutex.RLock()
-	defer battleMutex.RUnlock()
-	
-	// Get battle from memory
-	battle, exists := battleStore[battleId]
-	if !exists {
-		return nil, fmt.Errorf("battle not found")
+	battle, err := getBattleStore().Load(battleId)
+	if err != nil {
+		return nil, err
 	}
 	
-	// Verify the battle belongs to the user
-	if battle.UserId != userId {
-		return nil, fmt.Errorf("battle not found")
+	// Verify the user is one of the battle's players
+	if battle.sideOf(userId) == "" {
+		return nil, ErrBattleNotFound
 	}
 	
-	return battle, nil
-}
\ No newline at end of file
+	return enforceTurnTimeout(battle)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	StruggleMoveName       = "struggle"
+	StrugglePower          = 50
+	StruggleRecoilFraction = 4 // Struggle costs the user 1/4 of its max HP
+)
+
+// struggleChoice is the only attack left once every move is out of PP
+var struggleChoice = BattleChoice{Action: "attack", MoveName: StruggleMoveName}
+
+// struggleMove returns Struggle. It's typeless, never misses and uses no PP.
+func struggleMove() *PokemonMove {
+	return &PokemonMove{
+		Name:        StruggleMoveName,
+		Power:       StrugglePower,
+		Type:        "typeless",
+		DamageClass: "physical",
+	}
+}
+
+// isStruggle reports whether a move name is Struggle
+func isStruggle(moveName string) bool {
+	return strings.EqualFold(moveName, StruggleMoveName)
+}
+
+// mustStruggle reports whether a Pokemon has run out of PP on every move
+func mustStruggle(pokemon *BattlePokemon) bool {
+	return len(usableMoves(pokemon)) == 0
+}
+
+// moveFor returns the move a side chose, Struggle included, or nil
+func moveFor(pokemon *BattlePokemon, moveName string) *PokemonMove {
+	if isStruggle(moveName) {
+		return struggleMove()
+	}
+	return findMove(pokemon, moveName)
+}
+
+// validateAttack checks that the active Pokemon can use a move this turn. Struggle
+// is only allowed once every other move is out of PP.
+func validateAttack(pokemon *BattlePokemon, moveName string) error {
+	if isStruggle(moveName) {
+		if !mustStruggle(pokemon) {
+			return fmt.Errorf("%s can only struggle once all of its moves are out of PP", pokemon.Name)
+		}
+		return nil
+	}
+
+	move := findMove(pokemon, moveName)
+	if move == nil {
+		return fmt.Errorf("move %s not found", moveName)
+	}
+	if move.CurrentPP <= 0 {
+		return fmt.Errorf("move %s has no PP left", moveName)
+	}
+	return nil
+}
+
+// applyStruggleRecoil damages the user of Struggle and records it on the action
+func applyStruggleRecoil(attacker *BattlePokemon, action *TurnAction) {
+	recoil := attacker.MaxHP / StruggleRecoilFraction
+	if recoil < 1 {
+		recoil = 1
+	}
+	if recoil > attacker.CurrentHP {
+		recoil = attacker.CurrentHP
+	}
+
+	attacker.CurrentHP -= recoil
+	action.Recoil = recoil
+	action.Message += fmt.Sprintf(" %s is damaged by recoil!", attacker.Name)
+}
+
+// legalActions lists every choice a side can submit right now, so clients never
+// have to work out PP, fainted Pokemon or forced switches themselves. Sides that
+// aren't controlled by a user, spectators and finished battles have none.
+func (b *BattleState) legalActions(actor string) []BattleChoice {
+	if b.BattleStatus != "active" || actor == "" || !b.controlledByUser(actor) || b.activeIndex(actor) >= len(b.team(actor)) {
+		return nil
+	}
+
+	var actions []BattleChoice
+	_, chosen := b.PendingChoices[actor]
+	switch {
+	case chosen:
+		// Waiting for the opponent; giving up is all that's left
+	case b.CurrentTurn == "switch":
+		if b.mustSwitch(actor) {
+			actions = append(actions, b.legalSwitches(actor)...)
+		}
+	default:
+		pokemon := b.activePokemon(actor)
+		for _, i := range usableMoves(pokemon) {
+			actions = append(actions, BattleChoice{Action: "attack", MoveName: pokemon.Moves[i].Name})
+		}
+		if mustStruggle(pokemon) {
+			actions = append(actions, struggleChoice)
+		}
+		actions = append(actions, b.legalSwitches(actor)...)
+	}
+
+	actions = append(actions, BattleChoice{Action: "forfeit"})
+	if !b.isPvP() {
+		actions = append(actions, BattleChoice{Action: "run"})
+	}
+	return actions
+}
+
+// legalSwitches lists the team members a side can switch to
+func (b *BattleState) legalSwitches(actor string) []BattleChoice {
+	var switches []BattleChoice
+	team := b.team(actor)
+	for i := range team {
+		if validateSwitch(team, b.activeIndex(actor), i) == nil {
+			switches = append(switches, BattleChoice{Action: "switch", SwitchTo: i})
+		}
+	}
+	return switches
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"fmt"
+	"testing"
+)
+
+// drainPP uses up every move of a team's active Pokemon
+func drainPP(pokemon *BattlePokemon) {
+	for i := range pokemon.Moves {
+		pokemon.Moves[i].CurrentPP = 0
+	}
+}
+
+func TestStruggle(t *testing.T) {
+	battle := testAIBattle()
+	battle.CurrentTurn = "player"
+	battle.BattleStatus = "active"
+	battle.RNG = newBattleRNG(1)
+
+	if err := validateChoice(battle, "player", struggleChoice); err == nil {
+		t.Error("validateChoice() allowed struggling with PP left")
+	}
+
+	player := battle.activePokemon("player")
+	drainPP(player)
+	drainPP(battle.activePokemon("computer"))
+
+	turnResult, err := processBattleTurn(battle, struggleChoice)
+	if err != nil {
+		t.Fatalf("processBattleTurn() error = %v", err)
+	}
+
+	for _, action := range []*TurnAction{turnResult.PlayerAction, turnResult.ComputerAction} {
+		if action == nil || action.MoveName != StruggleMoveName || action.Missed || action.Damage == 0 {
+			t.Fatalf("action = %+v, want a struggle that hits", action)
+		}
+		if action.Recoil != 150/StruggleRecoilFraction {
+			t.Errorf("recoil = %d, want %d", action.Recoil, 150/StruggleRecoilFraction)
+		}
+	}
+	if player.CurrentHP != 150-turnResult.ComputerAction.Damage-turnResult.PlayerAction.Recoil {
+		t.Errorf("player HP = %d, want damage and recoil taken", player.CurrentHP)
+	}
+	for _, move := range player.Moves {
+		if move.CurrentPP < 0 {
+			t.Errorf("%s PP = %d, want it to stay at 0", move.Name, move.CurrentPP)
+		}
+	}
+}
+
+func TestStrategiesOnlyPickUsableMoves(t *testing.T) {
+	for name, strategy := range opponentStrategies {
+		t.Run(name, func(t *testing.T) {
+			battle := testAIBattle()
+			battle.RNG = newBattleRNG(1)
+			for i := 0; i < 50; i++ {
+				if choice := strategy.ChooseAction(battle, "computer"); choice.MoveName == "hydro-pump" {
+					t.Fatal("picked hydro-pump, which has no PP left")
+				}
+			}
+
+			drainPP(battle.activePokemon("computer"))
+			if choice := strategy.ChooseAction(battle, "computer"); choice != struggleChoice {
+				t.Errorf("with no PP left chose %+v, want struggle", choice)
+			}
+		})
+	}
+}
+
+func TestLegalActions(t *testing.T) {
+	second := testAIBattle().PlayerTeam[0]
+	second.Name = "bulbasaur"
+
+	tests := []struct {
+		name   string
+		setup  func(battle *BattleState)
+		viewer string
+		want   string
+	}{
+		{
+			name: "attacks and switches",
+			setup: func(battle *BattleState) {
+				battle.PlayerTeam = append(battle.PlayerTeam, second)
+			},
+			viewer: "player",
+			want:   "[{attack ember 0} {switch  1} {forfeit  0} {run  0}]",
+		},
+		{
+			name:   "no PP left",
+			setup:  func(battle *BattleState) { drainPP(battle.activePokemon("player")) },
+			viewer: "player",
+			want:   "[{attack struggle 0} {forfeit  0} {run  0}]",
+		},
+		{
+			name: "forced switch",
+			setup: func(battle *BattleState) {
+				battle.PlayerTeam = append(battle.PlayerTeam, second)
+				battle.PlayerTeam[0].CurrentHP = 0
+				battle.CurrentTurn = "switch"
+				battle.ForcedSwitches = []string{"player"}
+			},
+			viewer: "player",
+			want:   "[{switch  1} {forfeit  0} {run  0}]",
+		},
+		{
+			name: "waiting for the opponent",
+			setup: func(battle *BattleState) {
+				battle.Mode = BattleModePvP
+				battle.PendingChoices = map[string]BattleChoice{"computer": {Action: "attack", MoveName: "tackle"}}
+			},
+			viewer: "computer",
+			want:   "[{forfeit  0}]",
+		},
+		{name: "computer side", setup: func(battle *BattleState) {}, viewer: "computer", want: "[]"},
+		{name: "spectator", setup: func(battle *BattleState) {}, viewer: "", want: "[]"},
+		{name: "finished", setup: func(battle *BattleState) { battle.BattleStatus = "won" }, viewer: "player", want: "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			battle := testAIBattle()
+			battle.CurrentTurn = "player"
+			battle.BattleStatus = "active"
+			tt.setup(battle)
+
+			if got := fmt.Sprint(battle.viewFor(tt.viewer).LegalActions); got != tt.want {
+				t.Errorf("legal actions = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
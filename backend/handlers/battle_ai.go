@@ -0,0 +1,177 @@
+package handlers
+
+import "math"
+
+const (
+	DefaultDifficulty = "easy"
+	LookaheadDepth    = 3 // Turns the lookahead strategy searches ahead
+)
+
+// OpponentStrategy decides what the computer does each turn
+type OpponentStrategy interface {
+	Name() string
+	ChooseAction(battle *BattleState, actor string) BattleChoice
+}
+
+var opponentStrategies = map[string]OpponentStrategy{
+	"random":    randomStrategy{},
+	"greedy":    greedyStrategy{},
+	"lookahead": lookaheadStrategy{depth: LookaheadDepth},
+}
+
+// difficultyStrategies maps each selectable difficulty to the strategy it uses
+var difficultyStrategies = map[string]string{
+	"easy":   "random",
+	"normal": "greedy",
+	"hard":   "lookahead",
+}
+
+// strategyFor returns the battle's opponent strategy, defaulting to random for older battles
+func strategyFor(battle *BattleState) OpponentStrategy {
+	if strategy, ok := opponentStrategies[battle.OpponentStrategy]; ok {
+		return strategy
+	}
+	return opponentStrategies["random"]
+}
+
+// usableMoves returns the indexes of the moves that still have PP
+func usableMoves(pokemon *BattlePokemon) []int {
+	var indexes []int
+	for i, move := range pokemon.Moves {
+		if move.CurrentPP > 0 {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// expectedDamage estimates the average damage of a move, accounting for
+// accuracy, critical hits and the random factor
+func expectedDamage(field *FieldState, attacker *BattlePokemon, defender *BattlePokemon, move *PokemonMove) float64 {
+	if move.DamageClass == "status" {
+		return 0
+	}
+
+	normal, _, _ := baseDamage(field, attacker, defender, move, false)
+	critical, _, _ := baseDamage(field, attacker, defender, move, true)
+	critChance := criticalHitChance(move)
+	damage := (normal*(1-critChance) + critical*critChance) * 0.925
+	if move.MaxHits > 1 {
+		damage *= float64(max(move.MinHits, 1)+move.MaxHits) / 2
+	}
+
+	if move.Accuracy > 0 {
+		chance := float64(move.Accuracy) / 100 * accuracyStageMultiplier(attacker.StatStages.Accuracy-defender.StatStages.Evasion)
+		damage *= math.Min(1, chance)
+	}
+	return damage
+}
+
+// randomStrategy picks any usable move at random
+type randomStrategy struct{}
+
+func (randomStrategy) Name() string { return "random" }
+
+func (randomStrategy) ChooseAction(battle *BattleState, actor string) BattleChoice {
+	pokemon := battle.activePokemon(actor)
+	usable := usableMoves(pokemon)
+	if len(usable) == 0 {
+		return struggleChoice
+	}
+	return BattleChoice{Action: "attack", MoveName: pokemon.Moves[usable[battle.random().IntN(len(usable))]].Name}
+}
+
+// greedyStrategy picks the move with the highest expected damage this turn
+type greedyStrategy struct{}
+
+func (greedyStrategy) Name() string { return "greedy" }
+
+func (greedyStrategy) ChooseAction(battle *BattleState, actor string) BattleChoice {
+	attacker := battle.activePokemon(actor)
+	defender := battle.activePokemon(opponentOf(actor))
+	if mustStruggle(attacker) {
+		return struggleChoice
+	}
+
+	best := bestMoveBy(attacker, func(move *PokemonMove) float64 {
+		return expectedDamage(&battle.Field, attacker, defender, move)
+	})
+	return BattleChoice{Action: "attack", MoveName: attacker.Moves[best].Name}
+}
+
+// lookaheadStrategy searches several turns ahead assuming the opponent replies with
+// its best move, scoring positions by the remaining HP fraction of each side
+type lookaheadStrategy struct {
+	depth int
+}
+
+func (lookaheadStrategy) Name() string { return "lookahead" }
+
+func (s lookaheadStrategy) ChooseAction(battle *BattleState, actor string) BattleChoice {
+	self := battle.activePokemon(actor)
+	foe := battle.activePokemon(opponentOf(actor))
+	if mustStruggle(self) {
+		return struggleChoice
+	}
+
+	best := bestMoveBy(self, func(move *PokemonMove) float64 {
+		return s.search(&battle.Field, self, foe, move, float64(self.CurrentHP), float64(foe.CurrentHP), s.depth)
+	})
+	return BattleChoice{Action: "attack", MoveName: self.Moves[best].Name}
+}
+
+// search returns the value of using move this turn, given the current HP of both sides
+func (s lookaheadStrategy) search(field *FieldState, self, foe *BattlePokemon, move *PokemonMove, selfHP, foeHP float64, depth int) float64 {
+	worst := math.Inf(1)
+	for _, reply := range movesOrFirst(foe) {
+		newSelfHP, newFoeHP := simulateTurn(field, self, foe, move, &foe.Moves[reply], selfHP, foeHP)
+
+		value := newSelfHP/float64(self.MaxHP) - newFoeHP/float64(foe.MaxHP)
+		if depth > 1 && newSelfHP > 0 && newFoeHP > 0 {
+			value = math.Inf(-1)
+			for _, next := range movesOrFirst(self) {
+				value = math.Max(value, s.search(field, self, foe, &self.Moves[next], newSelfHP, newFoeHP, depth-1))
+			}
+		}
+		worst = math.Min(worst, value)
+	}
+	return worst
+}
+
+// simulateTurn applies the expected damage of both moves in turn order
+func simulateTurn(field *FieldState, self, foe *BattlePokemon, selfMove, foeMove *PokemonMove, selfHP, foeHP float64) (float64, float64) {
+	selfFirst := movesFirst(self, foe, selfMove, foeMove)
+
+	if selfFirst {
+		foeHP = math.Max(0, foeHP-expectedDamage(field, self, foe, selfMove))
+		if foeHP > 0 {
+			selfHP = math.Max(0, selfHP-expectedDamage(field, foe, self, foeMove))
+		}
+	} else {
+		selfHP = math.Max(0, selfHP-expectedDamage(field, foe, self, foeMove))
+		if selfHP > 0 {
+			foeHP = math.Max(0, foeHP-expectedDamage(field, self, foe, selfMove))
+		}
+	}
+	return selfHP, foeHP
+}
+
+// movesOrFirst returns the usable move indexes, or the first move if none have PP left
+func movesOrFirst(pokemon *BattlePokemon) []int {
+	if indexes := usableMoves(pokemon); len(indexes) > 0 {
+		return indexes
+	}
+	return []int{0}
+}
+
+// bestMoveBy returns the index of the usable move with the highest score
+func bestMoveBy(pokemon *BattlePokemon, score func(move *PokemonMove) float64) int {
+	candidates := movesOrFirst(pokemon)
+	best, bestScore := candidates[0], math.Inf(-1)
+	for _, i := range candidates {
+		if value := score(&pokemon.Moves[i]); value > bestScore {
+			best, bestScore = i, value
+		}
+	}
+	return best
+}
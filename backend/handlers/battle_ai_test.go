@@ -0,0 +1,49 @@
+package handlers
+
+import "testing"
+
+func testAIBattle() *BattleState {
+	stats := PokemonStats{HP: 150, Attack: 100, Defense: 100, SpecialAttack: 100, SpecialDefense: 100, Speed: 100}
+	return &BattleState{
+		PlayerTeam: []BattlePokemon{{
+			Name: "charmander", CurrentHP: 150, MaxHP: 150, Level: 50, Types: []string{"fire"}, Stats: stats,
+			Moves: []PokemonMove{{Name: "ember", Power: 40, Type: "fire", CurrentPP: 25, Accuracy: 100, DamageClass: "special"}},
+		}},
+		ComputerTeam: []BattlePokemon{{
+			Name: "squirtle", CurrentHP: 150, MaxHP: 150, Level: 50, Types: []string{"water"}, Stats: stats,
+			Moves: []PokemonMove{
+				{Name: "tackle", Power: 40, Type: "normal", CurrentPP: 35, Accuracy: 100, DamageClass: "physical"},
+				{Name: "water-gun", Power: 40, Type: "water", CurrentPP: 25, Accuracy: 100, DamageClass: "special"},
+				{Name: "tail-whip", Type: "normal", CurrentPP: 30, Accuracy: 100, DamageClass: "status"},
+				{Name: "hydro-pump", Power: 110, Type: "water", CurrentPP: 0, Accuracy: 80, DamageClass: "special"},
+			},
+		}},
+	}
+}
+
+func TestGreedyStrategyPrefersSuperEffectiveMoves(t *testing.T) {
+	choice := greedyStrategy{}.ChooseAction(testAIBattle(), "computer")
+	if choice.MoveName != "water-gun" {
+		t.Errorf("greedy chose %s, want water-gun", choice.MoveName)
+	}
+}
+
+func TestLookaheadStrategySkipsMovesWithoutPP(t *testing.T) {
+	choice := lookaheadStrategy{depth: LookaheadDepth}.ChooseAction(testAIBattle(), "computer")
+	if choice.MoveName != "water-gun" {
+		t.Errorf("lookahead chose %s, want water-gun", choice.MoveName)
+	}
+}
+
+func TestStrategyFor(t *testing.T) {
+	for difficulty, strategyName := range difficultyStrategies {
+		battle := &BattleState{Difficulty: difficulty, OpponentStrategy: strategyName}
+		if name := strategyFor(battle).Name(); name != strategyName {
+			t.Errorf("strategyFor(%s) = %s, want %s", difficulty, name, strategyName)
+		}
+	}
+
+	if name := strategyFor(&BattleState{}).Name(); name != "random" {
+		t.Errorf("strategyFor() with no strategy = %s, want random", name)
+	}
+}
@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+const (
+	LeftoversHealFraction   = 16 // Leftovers restores 1/16 of max HP each turn
+	LifeOrbMultiplier       = 1.3
+	LifeOrbRecoilFraction   = 10 // Life Orb costs 1/10 of max HP per hit
+	ExpertBeltMultiplier    = 1.2
+	RockyHelmetFraction     = 6 // Rocky Helmet hurts attackers by 1/6 of their max HP
+	RoughSkinFraction       = 8
+	AbsorbHealFraction      = 4 // Water Absorb and Volt Absorb restore 1/4 of max HP
+	SitrusBerryHealFraction = 4
+	OranBerryHeal           = 10
+	PinchAbilityMultiplier  = 1.5 // Blaze, Torrent, Overgrow and Swarm below 1/3 HP
+	ContactStatusChance     = 30  // Percent chance for Static, Flame Body and Poison Point
+)
+
+// ErrAbilityNotLegal is returned when a requested ability isn't one the Pokemon can have
+var ErrAbilityNotLegal = errors.New("ability not legal")
+
+// battleEffect is what an ability or held item does in battle. Every hook is optional
+// and returns a battle log message, or "" when nothing happened. Physical moves are
+// treated as making contact.
+type battleEffect struct {
+	// switchIn runs when the holder is sent out
+	switchIn func(battle *BattleState, holder, opponent *BattlePokemon) string
+	// beforeDamage scales the damage of a move the holder uses (attacking) or is hit
+	// by. It must not change anything, since the AI uses it to estimate damage.
+	beforeDamage func(holder, other *BattlePokemon, move *PokemonMove, attacking bool) (float64, string)
+	// afterDamage runs once a damaging move the holder used or was hit by has landed
+	afterDamage func(rng *rand.Rand, holder, other *BattlePokemon, move *PokemonMove, damage int, attacking bool) string
+	// endOfTurn runs at the end of every turn the holder is still standing
+	endOfTurn func(holder *BattlePokemon) string
+}
+
+// heldEffect is an ability or item effect along with where it came from
+type heldEffect struct {
+	source string // "ability" or "item"
+	name   string
+	battleEffect
+}
+
+// abilityEffects are the abilities battles support. Any other ability is kept on the
+// Pokemon but does nothing.
+var abilityEffects = map[string]battleEffect{
+	"intimidate": {
+		switchIn: func(battle *BattleState, holder, opponent *BattlePokemon) string {
+			if opponent.CurrentHP <= 0 {
+				return ""
+			}
+			_, messages := applyStatChanges(opponent, []StatChange{{Stat: "attack", Change: -1}})
+			return fmt.Sprintf("%s's intimidate! %s", holder.Name, strings.Join(messages, " "))
+		},
+	},
+	"drizzle":        weatherAbility("drizzle", WeatherRain),
+	"drought":        weatherAbility("drought", WeatherSun),
+	"sand-stream":    weatherAbility("sand-stream", WeatherSandstorm),
+	"snow-warning":   weatherAbility("snow-warning", WeatherHail),
+	"electric-surge": terrainAbility("electric-surge", TerrainElectric),
+	"grassy-surge":   terrainAbility("grassy-surge", TerrainGrassy),
+	"psychic-surge":  terrainAbility("psychic-surge", TerrainPsychic),
+	"misty-surge":    terrainAbility("misty-surge", TerrainMisty),
+	"levitate":       immunityAbility("levitate", "ground"),
+	"water-absorb":   absorbAbility("water-absorb", "water"),
+	"volt-absorb":    absorbAbility("volt-absorb", "electric"),
+	"thick-fat": {
+		beforeDamage: func(holder, other *BattlePokemon, move *PokemonMove, attacking bool) (float64, string) {
+			if attacking || (move.Type != "fire" && move.Type != "ice") {
+				return 1, ""
+			}
+			return 0.5, fmt.Sprintf("%s's thick-fat weakened the attack!", holder.Name)
+		},
+	},
+	"blaze":    pinchAbility("blaze", "fire"),
+	"torrent":  pinchAbility("torrent", "water"),
+	"overgrow": pinchAbility("overgrow", "grass"),
+	"swarm":    pinchAbility("swarm", "bug"),
+	"huge-power": {
+		beforeDamage: func(holder, other *BattlePokemon, move *PokemonMove, attacking bool) (float64, string) {
+			if attacking && move.DamageClass == "physical" {
+				return 2, ""
+			}
+			return 1, ""
+		},
+	},
+	"static":       contactStatusAbility("static", StatusParalysis),
+	"flame-body":   contactStatusAbility("flame-body", StatusBurn),
+	"poison-point": contactStatusAbility("poison-point", StatusPoison),
+	"rough-skin": {
+		afterDamage: func(rng *rand.Rand, holder, other *BattlePokemon, move *PokemonMove, damage int, attacking bool) string {
+			if attacking || damage == 0 || move.DamageClass != "physical" || other.CurrentHP <= 0 {
+				return ""
+			}
+			hurt(other, other.MaxHP/RoughSkinFraction)
+			return fmt.Sprintf("%s was hurt by %s's rough-skin!", other.Name, holder.Name)
+		},
+	},
+	"speed-boost": {
+		endOfTurn: func(holder *BattlePokemon) string {
+			applied, messages := applyStatChanges(holder, []StatChange{{Stat: "speed", Change: 1}})
+			if len(applied) == 0 {
+				return ""
+			}
+			return fmt.Sprintf("%s's speed-boost! %s", holder.Name, strings.Join(messages, " "))
+		},
+	},
+}
+
+// itemEffects are the held items battles support; anything else is rejected
+var itemEffects = map[string]battleEffect{
+	"leftovers": {
+		endOfTurn: func(holder *BattlePokemon) string {
+			if holder.CurrentHP >= holder.MaxHP {
+				return ""
+			}
+			heal(holder, holder.MaxHP/LeftoversHealFraction)
+			return fmt.Sprintf("%s restored a little HP using its leftovers!", holder.Name)
+		},
+	},
+	"sitrus-berry": berryItem("sitrus-berry", func(holder *BattlePokemon) int { return holder.MaxHP / SitrusBerryHealFraction }),
+	"oran-berry":   berryItem("oran-berry", func(holder *BattlePokemon) int { return OranBerryHeal }),
+	"life-orb": {
+		beforeDamage: func(holder, other *BattlePokemon, move *PokemonMove, attacking bool) (float64, string) {
+			if !attacking {
+				return 1, ""
+			}
+			return LifeOrbMultiplier, ""
+		},
+		afterDamage: func(rng *rand.Rand, holder, other *BattlePokemon, move *PokemonMove, damage int, attacking bool) string {
+			if !attacking || damage == 0 || holder.CurrentHP <= 0 {
+				return ""
+			}
+			hurt(holder, holder.MaxHP/LifeOrbRecoilFraction)
+			return fmt.Sprintf("%s lost some of its HP to its life-orb!", holder.Name)
+		},
+	},
+	"expert-belt": {
+		beforeDamage: func(holder, other *BattlePokemon, move *PokemonMove, attacking bool) (float64, string) {
+			if attacking && typeEffectiveness(move.Type, other.Types) > 1 {
+				return ExpertBeltMultiplier, ""
+			}
+			return 1, ""
+		},
+	},
+	"rocky-helmet": {
+		afterDamage: func(rng *rand.Rand, holder, other *BattlePokemon, move *PokemonMove, damage int, attacking bool) string {
+			if attacking || damage == 0 || move.DamageClass != "physical" || other.CurrentHP <= 0 {
+				return ""
+			}
+			hurt(other, other.MaxHP/RockyHelmetFraction)
+			return fmt.Sprintf("%s was hurt by %s's rocky-helmet!", other.Name, holder.Name)
+		},
+	},
+}
+
+// weatherAbility sets the weather when the holder is sent out
+func weatherAbility(name, weather string) battleEffect {
+	return battleEffect{
+		switchIn: func(battle *BattleState, holder, opponent *BattlePokemon) string {
+			if message := battle.Field.setWeather(weather); message != "" {
+				return fmt.Sprintf("%s's %s! %s", holder.Name, name, message)
+			}
+			return ""
+		},
+	}
+}
+
+// terrainAbility sets the terrain when the holder is sent out
+func terrainAbility(name, terrain string) battleEffect {
+	return battleEffect{
+		switchIn: func(battle *BattleState, holder, opponent *BattlePokemon) string {
+			if message := battle.Field.setTerrain(terrain); message != "" {
+				return fmt.Sprintf("%s's %s! %s", holder.Name, name, message)
+			}
+			return ""
+		},
+	}
+}
+
+// immunityAbility makes the holder immune to moves of one type
+func immunityAbility(name, moveType string) battleEffect {
+	return battleEffect{
+		beforeDamage: func(holder, other *BattlePokemon, move *PokemonMove, attacking bool) (float64, string) {
+			if attacking || move.Type != moveType {
+				return 1, ""
+			}
+			return 0, fmt.Sprintf("%s's %s makes it immune!", holder.Name, name)
+		},
+	}
+}
+
+// absorbAbility makes the holder immune to moves of one type and heals it when hit by one
+func absorbAbility(name, moveType string) battleEffect {
+	effect := immunityAbility(name, moveType)
+	effect.afterDamage = func(rng *rand.Rand, holder, other *BattlePokemon, move *PokemonMove, damage int, attacking bool) string {
+		if attacking || move.Type != moveType || holder.CurrentHP >= holder.MaxHP {
+			return ""
+		}
+		heal(holder, holder.MaxHP/AbsorbHealFraction)
+		return fmt.Sprintf("%s restored HP using its %s!", holder.Name, name)
+	}
+	return effect
+}
+
+// pinchAbility powers up moves of one type once the holder is down to 1/3 of its HP
+func pinchAbility(name, moveType string) battleEffect {
+	return battleEffect{
+		beforeDamage: func(holder, other *BattlePokemon, move *PokemonMove, attacking bool) (float64, string) {
+			if !attacking || move.Type != moveType || holder.CurrentHP*3 > holder.MaxHP {
+				return 1, ""
+			}
+			return PinchAbilityMultiplier, fmt.Sprintf("%s's %s powered up the attack!", holder.Name, name)
+		},
+	}
+}
+
+// contactStatusAbility may inflict a status on Pokemon that hit the holder with a contact move
+func contactStatusAbility(name, status string) battleEffect {
+	return battleEffect{
+		afterDamage: func(rng *rand.Rand, holder, other *BattlePokemon, move *PokemonMove, damage int, attacking bool) string {
+			if attacking || damage == 0 || move.DamageClass != "physical" {
+				return ""
+			}
+			if rng.IntN(100) >= ContactStatusChance || !inflictStatus(rng, other, status) {
+				return ""
+			}
+			return fmt.Sprintf("%s's %s! %s", holder.Name, name, statusInflictedMessage(other))
+		},
+	}
+}
+
+// berryItem is eaten to restore HP once a hit leaves the holder at half HP or less
+func berryItem(name string, amount func(holder *BattlePokemon) int) battleEffect {
+	return battleEffect{
+		afterDamage: func(rng *rand.Rand, holder, other *BattlePokemon, move *PokemonMove, damage int, attacking bool) string {
+			if attacking || damage == 0 || holder.CurrentHP <= 0 || holder.CurrentHP*2 > holder.MaxHP {
+				return ""
+			}
+			heal(holder, amount(holder))
+			holder.ItemConsumed = true
+			return fmt.Sprintf("%s ate its %s and restored HP!", holder.Name, name)
+		},
+	}
+}
+
+// heal restores HP without going over the Pokemon's max HP
+func heal(pokemon *BattlePokemon, amount int) {
+	if amount < 1 {
+		amount = 1
+	}
+	pokemon.CurrentHP = min(pokemon.MaxHP, pokemon.CurrentHP+amount)
+}
+
+// hurt takes away HP without going below zero
+func hurt(pokemon *BattlePokemon, amount int) {
+	if amount < 1 {
+		amount = 1
+	}
+	pokemon.CurrentHP = max(0, pokemon.CurrentHP-amount)
+}
+
+// effectsOf returns the effects of a Pokemon's ability and held item. Consumed
+// items have no effect.
+func effectsOf(pokemon *BattlePokemon) []heldEffect {
+	var effects []heldEffect
+	if effect, ok := abilityEffects[pokemon.Ability]; ok {
+		effects = append(effects, heldEffect{source: "ability", name: pokemon.Ability, battleEffect: effect})
+	}
+	if effect, ok := itemEffects[pokemon.Item]; ok && !pokemon.ItemConsumed {
+		effects = append(effects, heldEffect{source: "item", name: pokemon.Item, battleEffect: effect})
+	}
+	return effects
+}
+
+// damageModifier combines the before-damage effects of both Pokemon on a move,
+// along with the messages explaining them
+func damageModifier(attacker, defender *BattlePokemon, move *PokemonMove) (float64, []string) {
+	multiplier := 1.0
+	var messages []string
+	apply := func(holder, other *BattlePokemon, attacking bool) {
+		for _, effect := range effectsOf(holder) {
+			if effect.beforeDamage == nil {
+				continue
+			}
+			value, message := effect.beforeDamage(holder, other, move, attacking)
+			multiplier *= value
+			if message != "" {
+				messages = append(messages, message)
+			}
+		}
+	}
+	apply(attacker, defender, true)
+	apply(defender, attacker, false)
+	return multiplier, messages
+}
+
+// afterDamageEffects runs the after-damage effects of one side of an attack. The
+// defender's run after every hit of a multi-hit move, so berries and Rocky Helmet
+// trigger per hit; the attacker's, like Life Orb, run once per move.
+func afterDamageEffects(rng *rand.Rand, holder, other *BattlePokemon, move *PokemonMove, damage int, attacking bool) []string {
+	var messages []string
+	for _, effect := range effectsOf(holder) {
+		if effect.afterDamage == nil {
+			continue
+		}
+		if message := effect.afterDamage(rng, holder, other, move, damage, attacking); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// switchInEffects runs the switch-in effects of a side's active Pokemon
+func switchInEffects(battle *BattleState, actor string) []string {
+	holder := battle.activePokemon(actor)
+	effects := effectsOf(holder)
+	if len(effects) == 0 {
+		return nil
+	}
+	opponent := battle.activePokemon(opponentOf(actor))
+
+	var messages []string
+	for _, effect := range effects {
+		if effect.switchIn == nil {
+			continue
+		}
+		if message := effect.switchIn(battle, holder, opponent); message != "" {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// endOfTurnEffects runs the end-of-turn effects of a side's active Pokemon and
+// logs each one as an "ability" or "item" action
+func endOfTurnEffects(battle *BattleState, actor string, turnNumber int, timestamp string) []*TurnAction {
+	holder := battle.activePokemon(actor)
+
+	var actions []*TurnAction
+	for _, effect := range effectsOf(holder) {
+		if effect.endOfTurn == nil || holder.CurrentHP <= 0 {
+			continue
+		}
+		if message := effect.endOfTurn(holder); message != "" {
+			actions = append(actions, &TurnAction{
+				Turn:          turnNumber,
+				Actor:         actor,
+				Action:        effect.source,
+				MoveName:      effect.name,
+				Effectiveness: 1,
+				Message:       message,
+				Timestamp:     timestamp,
+			})
+		}
+	}
+	return actions
+}
+
+// applyLeadEffects runs the switch-in effects of both leads when a battle starts.
+// They're logged as turn 0, before the first turn is played.
+func applyLeadEffects(battle *BattleState, timestamp string) {
+	for _, actor := range []string{"player", "computer"} {
+		for _, message := range switchInEffects(battle, actor) {
+			battle.TurnHistory = append(battle.TurnHistory, TurnAction{
+				Actor:         actor,
+				Action:        "ability",
+				MoveName:      battle.activePokemon(actor).Ability,
+				Effectiveness: 1,
+				Message:       message,
+				Timestamp:     timestamp,
+			})
+		}
+	}
+}
+
+// pokemonAbility is one of the abilities PokeAPI lists for a Pokemon
+type pokemonAbility struct {
+	Name   string
+	Hidden bool
+}
+
+// parseAbilities extracts a Pokemon's legal abilities from PokeAPI's abilities array
+func parseAbilities(abilitiesData []interface{}) []pokemonAbility {
+	var abilities []pokemonAbility
+	for _, abilityInfo := range abilitiesData {
+		abilityMap, ok := abilityInfo.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		abilityData, ok := abilityMap["ability"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := abilityData["name"].(string)
+		if !ok {
+			continue
+		}
+		hidden, _ := abilityMap["is_hidden"].(bool)
+		abilities = append(abilities, pokemonAbility{Name: name, Hidden: hidden})
+	}
+	return abilities
+}
+
+// selectAbility returns the requested ability if the Pokemon can have it, or its
+// first regular ability when none was requested
+func selectAbility(pokemonName string, abilities []pokemonAbility, requested string) (string, error) {
+	if requested != "" {
+		for _, ability := range abilities {
+			if strings.EqualFold(ability.Name, requested) {
+				return ability.Name, nil
+			}
+		}
+		return "", fmt.Errorf("%w: %s can't have %s", ErrAbilityNotLegal, pokemonName, requested)
+	}
+
+	for _, ability := range abilities {
+		if !ability.Hidden {
+			return ability.Name, nil
+		}
+	}
+	if len(abilities) > 0 {
+		return abilities[0].Name, nil
+	}
+	return "", nil
+}
+
+// validateLoadout checks the abilities and held items a user asked for. Abilities
+// are checked against each Pokemon once it's fetched.
+func validateLoadout(teamSize int, abilities, items []string) error {
+	if len(abilities) > teamSize {
+		return fmt.Errorf("more abilities than team members")
+	}
+	if len(items) > teamSize {
+		return fmt.Errorf("more items than team members")
+	}
+	for _, item := range items {
+		if _, ok := itemEffects[item]; item != "" && !ok {
+			return fmt.Errorf("unknown item %s", item)
+		}
+	}
+	return nil
+}
+
+// isLoadoutError reports whether a team couldn't be fetched because of a move or
+// ability the user asked for, rather than a PokeAPI failure
+func isLoadoutError(err error) bool {
+	return errors.Is(err, ErrMoveNotLearnable) || errors.Is(err, ErrAbilityNotLegal)
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestDamageModifier(t *testing.T) {
+	ember := &PokemonMove{Name: "ember", Power: 40, Type: "fire", DamageClass: "special"}
+	earthquake := &PokemonMove{Name: "earthquake", Power: 100, Type: "ground", DamageClass: "physical"}
+
+	tests := []struct {
+		name     string
+		attacker BattlePokemon
+		defender BattlePokemon
+		move     *PokemonMove
+		want     float64
+	}{
+		{"no effects", BattlePokemon{}, BattlePokemon{}, ember, 1},
+		{"levitate", BattlePokemon{}, BattlePokemon{Ability: "levitate"}, earthquake, 0},
+		{"thick fat", BattlePokemon{}, BattlePokemon{Ability: "thick-fat"}, ember, 0.5},
+		{"blaze above a third", BattlePokemon{Ability: "blaze", CurrentHP: 100, MaxHP: 100}, BattlePokemon{}, ember, 1},
+		{"blaze in a pinch", BattlePokemon{Ability: "blaze", CurrentHP: 30, MaxHP: 100}, BattlePokemon{}, ember, PinchAbilityMultiplier},
+		{"life orb", BattlePokemon{Item: "life-orb"}, BattlePokemon{}, ember, LifeOrbMultiplier},
+		{"consumed item", BattlePokemon{Item: "life-orb", ItemConsumed: true}, BattlePokemon{}, ember, 1},
+		{"expert belt", BattlePokemon{Item: "expert-belt"}, BattlePokemon{Types: []string{"grass"}}, ember, ExpertBeltMultiplier},
+		{"huge power special move", BattlePokemon{Ability: "huge-power"}, BattlePokemon{}, ember, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _ := damageModifier(&tt.attacker, &tt.defender, tt.move); got != tt.want {
+				t.Errorf("damageModifier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAbsorbAbilityHeals(t *testing.T) {
+	battle := testAIBattle()
+	battle.RNG = newBattleRNG(1)
+	attacker := battle.activePokemon("computer")
+	defender := battle.activePokemon("player")
+	defender.Ability = "water-absorb"
+	defender.CurrentHP = 100
+
+	action := executeMove(battle.random().Rand, nil, attacker, defender, &attacker.Moves[1], "computer", 1, "")
+	if action.Damage != 0 || action.Effectiveness != 0 {
+		t.Errorf("damage = %d, effectiveness = %v, want the move absorbed", action.Damage, action.Effectiveness)
+	}
+	if want := 100 + 150/AbsorbHealFraction; defender.CurrentHP != want {
+		t.Errorf("HP = %d, want %d", defender.CurrentHP, want)
+	}
+}
+
+func TestBerryIsConsumed(t *testing.T) {
+	battle := testAIBattle()
+	battle.RNG = newBattleRNG(1)
+	attacker := battle.activePokemon("computer")
+	defender := battle.activePokemon("player")
+	defender.Item = "sitrus-berry"
+	defender.CurrentHP = 80
+
+	action := executeMove(battle.random().Rand, nil, attacker, defender, &attacker.Moves[0], "computer", 1, "")
+	if want := 80 - action.Damage + 150/SitrusBerryHealFraction; defender.CurrentHP != want {
+		t.Errorf("HP = %d, want %d", defender.CurrentHP, want)
+	}
+	if !defender.ItemConsumed {
+		t.Error("ItemConsumed = false, want the berry eaten")
+	}
+	if len(effectsOf(defender)) != 0 {
+		t.Error("a consumed berry still has an effect")
+	}
+}
+
+func TestMultiHitTriggersEffectsPerHit(t *testing.T) {
+	battle := testAIBattle()
+	battle.RNG = newBattleRNG(1)
+	attacker := battle.activePokemon("player")
+	defender := battle.activePokemon("computer")
+	defender.Item = "rocky-helmet"
+	doubleKick := &PokemonMove{Name: "double-kick", Power: 30, Type: "fighting", CurrentPP: 30, DamageClass: "physical", MinHits: 2, MaxHits: 2}
+
+	action := executeMove(battle.random().Rand, nil, attacker, defender, doubleKick, "player", 1, "")
+	if action.Hits != 2 {
+		t.Fatalf("hits = %d, want 2", action.Hits)
+	}
+	if want := 150 - 2*(150/RockyHelmetFraction); attacker.CurrentHP != want {
+		t.Errorf("attacker HP = %d, want %d after two rocky-helmet hits", attacker.CurrentHP, want)
+	}
+}
+
+func TestLeadAndEndOfTurnEffects(t *testing.T) {
+	battle := testAIBattle()
+	battle.CurrentTurn = "player"
+	battle.BattleStatus = "active"
+	battle.RNG = newBattleRNG(1)
+	battle.PlayerTeam[0].Ability = "intimidate"
+	battle.PlayerTeam[0].Item = "leftovers"
+	battle.PlayerTeam[0].CurrentHP = 100
+	battle.TurnHistory = []TurnAction{}
+
+	applyLeadEffects(battle, "")
+	if got := battle.activePokemon("computer").StatStages.Attack; got != -1 {
+		t.Errorf("computer attack stage = %d, want -1", got)
+	}
+	if len(battle.TurnHistory) != 1 || battle.TurnHistory[0].Action != "ability" || nextTurnNumber(battle) != 1 {
+		t.Fatalf("history = %+v, want one turn 0 ability action", battle.TurnHistory)
+	}
+
+	turnResult, err := processBattleTurn(battle, BattleChoice{Action: "attack", MoveName: "ember"})
+	if err != nil {
+		t.Fatalf("processBattleTurn() error = %v", err)
+	}
+
+	var healed bool
+	for _, action := range turnResult.StatusEvents {
+		healed = healed || (action.Action == "item" && action.MoveName == "leftovers")
+	}
+	if !healed {
+		t.Errorf("status events = %+v, want a leftovers heal", turnResult.StatusEvents)
+	}
+}
+
+func TestSelectAbility(t *testing.T) {
+	abilities := []pokemonAbility{{Name: "blaze"}, {Name: "solar-power", Hidden: true}}
+
+	tests := []struct {
+		name      string
+		abilities []pokemonAbility
+		requested string
+		want      string
+		wantErr   bool
+	}{
+		{"default", abilities, "", "blaze", false},
+		{"hidden ability", abilities, "Solar-Power", "solar-power", false},
+		{"not legal", abilities, "levitate", "", true},
+		{"none listed", nil, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectAbility("charmander", tt.abilities, tt.requested)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrAbilityNotLegal)) {
+				t.Fatalf("selectAbility() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("selectAbility() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateLoadout(t *testing.T) {
+	if err := validateLoadout(2, []string{"blaze"}, []string{"leftovers", ""}); err != nil {
+		t.Errorf("validateLoadout() error = %v", err)
+	}
+	if err := validateLoadout(1, nil, []string{"choice-band"}); err == nil {
+		t.Error("validateLoadout() accepted an unsupported item")
+	}
+	if err := validateLoadout(1, []string{"blaze", "blaze"}, nil); err == nil {
+		t.Error("validateLoadout() accepted more abilities than team members")
+	}
+}
@@ -0,0 +1,449 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/middleware"
+)
+
+const (
+	EventBufferSize        = 32               // Events queued per subscriber before new ones are dropped
+	EventHeartbeatInterval = 15 * time.Second // Keeps idle streams open through proxies
+	StreamTokenTTL         = 5 * time.Minute  // How long a stream token can open or reconnect a stream
+)
+
+// StreamToken lets a player's browser open an event stream with EventSource, which
+// can't send an Authorization header
+type StreamToken struct {
+	Token     string `json:"token"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+type StreamTokenResponse struct {
+	Token     string `json:"token,omitempty"`
+	Path      string `json:"path,omitempty"` // Relative link for streaming, e.g. /battle-events/{battleId}?token=...
+	ExpiresAt string `json:"expiresAt,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BattleEvent is pushed to clients streaming a battle
+type BattleEvent struct {
+	Type         string       `json:"type"` // "state", "start", "choice", "turn", "status", "hp" or "end"
+	BattleId     string       `json:"battleId"`
+	Battle       *BattleState `json:"battle,omitempty"`     // Current state, for "state", "start" and "turn"
+	TurnResult   *TurnResult  `json:"turnResult,omitempty"` // For "turn"
+	Status       *TurnAction  `json:"status,omitempty"`     // Status tick, faint or forced switch, for "status"
+	HP           *HPChange    `json:"hp,omitempty"`         // For "hp"
+	Side         string       `json:"side,omitempty"`       // Side that submitted a choice, for "choice"
+	Winner       string       `json:"winner,omitempty"`     // For "end"
+	BattleStatus string       `json:"battleStatus,omitempty"`
+	Timestamp    string       `json:"timestamp"`
+}
+
+// HPChange reports a Pokemon's HP after a turn
+type HPChange struct {
+	Side      string `json:"side"` // "player" or "computer"
+	Index     int    `json:"index"`
+	Name      string `json:"name"`
+	CurrentHP int    `json:"currentHp"`
+	MaxHP     int    `json:"maxHp"`
+}
+
+// battleEventHub fans battle events out to streaming clients. Subscribers only
+// receive events published by this server instance.
+type battleEventHub struct {
+	subscribers map[string]map[chan BattleEvent]struct{}
+	mutex       *sync.Mutex
+}
+
+var battleEvents = newBattleEventHub()
+
+func newBattleEventHub() *battleEventHub {
+	return &battleEventHub{
+		subscribers: make(map[string]map[chan BattleEvent]struct{}),
+		mutex:       &sync.Mutex{},
+	}
+}
+
+// subscribe registers for a battle's events. Call the returned function to unsubscribe.
+func (h *battleEventHub) subscribe(battleId string) (chan BattleEvent, func()) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	events := make(chan BattleEvent, EventBufferSize)
+	if h.subscribers[battleId] == nil {
+		h.subscribers[battleId] = make(map[chan BattleEvent]struct{})
+	}
+	h.subscribers[battleId][events] = struct{}{}
+
+	return events, func() {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+
+		delete(h.subscribers[battleId], events)
+		if len(h.subscribers[battleId]) == 0 {
+			delete(h.subscribers, battleId)
+		}
+	}
+}
+
+// publish sends events to every subscriber of a battle without blocking.
+// Events are dropped for subscribers that have fallen too far behind.
+func (h *battleEventHub) publish(battleId string, events ...BattleEvent) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	for subscriber := range h.subscribers[battleId] {
+		for _, event := range events {
+			select {
+			case subscriber <- event:
+			default:
+				log.Printf("Dropping %s event for slow subscriber of battle %s", event.Type, battleId)
+			}
+		}
+	}
+}
+
+// teamHP records the HP of every Pokemon on both sides, keyed by side
+func teamHP(battle *BattleState) map[string][]int {
+	hp := make(map[string][]int)
+	for _, side := range []string{"player", "computer"} {
+		for _, pokemon := range battle.team(side) {
+			hp[side] = append(hp[side], pokemon.CurrentHP)
+		}
+	}
+	return hp
+}
+
+// turnEvents builds the events for a resolved turn. hpBefore is the teamHP from before the turn.
+func turnEvents(battle *BattleState, hpBefore map[string][]int, turnResult *TurnResult) []BattleEvent {
+	now := time.Now().Format(time.RFC3339)
+	events := []BattleEvent{{
+		Type:       "turn",
+		BattleId:   battle.BattleId,
+		Battle:     battle.viewFor(""),
+		TurnResult: turnResult,
+		Timestamp:  now,
+	}}
+
+	for i := range turnResult.StatusEvents {
+		events = append(events, BattleEvent{
+			Type:      "status",
+			BattleId:  battle.BattleId,
+			Status:    &turnResult.StatusEvents[i],
+			Timestamp: now,
+		})
+	}
+
+	for _, side := range []string{"player", "computer"} {
+		for i, pokemon := range battle.team(side) {
+			if i < len(hpBefore[side]) && hpBefore[side][i] == pokemon.CurrentHP {
+				continue
+			}
+			events = append(events, BattleEvent{
+				Type:     "hp",
+				BattleId: battle.BattleId,
+				HP: &HPChange{
+					Side:      side,
+					Index:     i,
+					Name:      pokemon.Name,
+					CurrentHP: pokemon.CurrentHP,
+					MaxHP:     pokemon.MaxHP,
+				},
+				Timestamp: now,
+			})
+		}
+	}
+
+	if turnResult.BattleEnded {
+		events = append(events, BattleEvent{
+			Type:         "end",
+			BattleId:     battle.BattleId,
+			Winner:       turnResult.Winner,
+			BattleStatus: battle.BattleStatus,
+			Timestamp:    now,
+		})
+	}
+	return events
+}
+
+// writeEvent writes one server-sent event and flushes it to the client
+func writeEvent(w http.ResponseWriter, flusher http.Flusher, event BattleEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
+
+// streamBattle sends the current state, then relays live events until the battle
+// ends or the client disconnects
+func streamBattle(w http.ResponseWriter, r *http.Request, battle *BattleState, viewer string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(GetBattleResponse{Error: "Streaming not supported"})
+		return
+	}
+
+	// Subscribe before sending the snapshot so no turn is missed in between
+	events, unsubscribe := battleEvents.subscribe(battle.BattleId)
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	snapshot := BattleEvent{
+		Type:      "state",
+		BattleId:  battle.BattleId,
+		Battle:    battle.viewFor(viewer),
+		Timestamp: time.Now().Format(time.RFC3339),
+	}
+	if err := writeEvent(w, flusher, snapshot); err != nil || battle.finished() {
+		return
+	}
+
+	heartbeat := time.NewTicker(EventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+			// Nobody else may load the battle while a player is away, so streams apply
+			// due turn timeouts; the resulting events arrive through the hub
+			if latest, err := getBattleStore().Load(battle.BattleId); err == nil {
+				if _, err := enforceTurnTimeout(latest); err != nil {
+					log.Printf("Error applying turn timeout: %v", err)
+				}
+			}
+		case event := <-events:
+			if err := writeEvent(w, flusher, event); err != nil {
+				log.Printf("Error writing battle event: %v", err)
+				return
+			}
+			if event.Type == "end" {
+				return
+			}
+		}
+	}
+}
+
+// BattleEventsHandler streams live updates for a battle as server-sent events
+func BattleEventsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(GetBattleResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(GetBattleResponse{Error: "Authentication required"})
+		return
+	}
+
+	// Extract battle ID from URL path
+	// Expected format: /battle/{battleId}/events
+	battleId := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/battle/"), "/events")
+	if battleId == "" || strings.Contains(battleId, "/") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(GetBattleResponse{Error: "Battle ID required"})
+		return
+	}
+
+	battle, err := loadBattleState(battleId, user.Sub)
+	if err != nil {
+		log.Printf("Error loading battle state: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(GetBattleResponse{Error: "Battle not found"})
+		return
+	}
+
+	log.Printf("User %s streaming battle %s", user.Username, battleId)
+	streamBattle(w, r, battle, battle.sideOf(user.Sub))
+}
+
+// loadStreamedBattle loads a battle for a player streaming it with a stream token,
+// and returns the side the token was minted for
+func loadStreamedBattle(battleId, token string, now time.Time) (*BattleState, string, error) {
+	battle, err := getBattleStore().Load(battleId)
+	if err != nil {
+		return nil, "", err
+	}
+
+	for side, streamToken := range battle.StreamTokens {
+		if token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(streamToken.Token)) != 1 {
+			continue
+		}
+		expiresAt, err := time.Parse(time.RFC3339, streamToken.ExpiresAt)
+		if err != nil || !now.Before(expiresAt) {
+			return nil, "", ErrBattleNotFound
+		}
+		battle, err = enforceTurnTimeout(battle)
+		if err != nil {
+			return nil, "", err
+		}
+		return battle, side, nil
+	}
+	return nil, "", ErrBattleNotFound
+}
+
+// CreateStreamTokenHandler mints a short-lived token a player can use to stream
+// their battle from a browser. Minting a new token replaces the side's previous one.
+func CreateStreamTokenHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(StreamTokenResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(StreamTokenResponse{Error: "Authentication required"})
+		return
+	}
+
+	// Extract battle ID from URL path
+	// Expected format: /battle/{battleId}/stream-token
+	battleId := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/battle/"), "/stream-token")
+	if battleId == "" || strings.Contains(battleId, "/") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(StreamTokenResponse{Error: "Battle ID required"})
+		return
+	}
+
+	battle, err := loadBattleState(battleId, user.Sub)
+	if err != nil {
+		log.Printf("Error loading battle state: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(StreamTokenResponse{Error: "Battle not found"})
+		return
+	}
+
+	token, err := newSpectatorToken()
+	if err != nil {
+		log.Printf("Error creating stream token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(StreamTokenResponse{Error: "Failed to create stream token"})
+		return
+	}
+	expiresAt := time.Now().Add(StreamTokenTTL).Format(time.RFC3339)
+	if battle.StreamTokens == nil {
+		battle.StreamTokens = make(map[string]StreamToken)
+	}
+	battle.StreamTokens[battle.sideOf(user.Sub)] = StreamToken{Token: token, ExpiresAt: expiresAt}
+
+	if err := saveBattleState(battle); err != nil {
+		log.Printf("Error saving battle state: %v", err)
+		if errors.Is(err, ErrBattleConflict) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(StreamTokenResponse{Error: "Battle was updated by another request, please try again"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(StreamTokenResponse{Error: "Failed to save battle state"})
+		return
+	}
+
+	log.Printf("User %s created stream token for battle %s", user.Username, battleId)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StreamTokenResponse{
+		Token:     token,
+		Path:      fmt.Sprintf("/battle-events/%s?token=%s", battleId, token),
+		ExpiresAt: expiresAt,
+	})
+}
+
+// StreamBattleEventsHandler streams a battle to a player holding a stream token. It
+// is the browser-friendly version of BattleEventsHandler, which needs an
+// Authorization header.
+func StreamBattleEventsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(GetBattleResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Extract battle ID from URL path
+	// Expected format: /battle-events/{battleId}
+	battleId := strings.TrimPrefix(r.URL.Path, "/battle-events/")
+	if battleId == "" || strings.Contains(battleId, "/") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(GetBattleResponse{Error: "Battle ID required"})
+		return
+	}
+
+	battle, side, err := loadStreamedBattle(battleId, r.URL.Query().Get("token"), time.Now())
+	if err != nil {
+		log.Printf("Error loading streamed battle: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(GetBattleResponse{Error: "Battle not found"})
+		return
+	}
+
+	log.Printf("Streaming battle %s to the %s side with a stream token", battleId, side)
+	streamBattle(w, r, battle, side)
+}
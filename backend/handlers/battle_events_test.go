@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBattleEventHub(t *testing.T) {
+	hub := newBattleEventHub()
+	events, unsubscribe := hub.subscribe("battle")
+
+	hub.publish("other", BattleEvent{Type: "turn"})
+	hub.publish("battle", BattleEvent{Type: "turn"}, BattleEvent{Type: "end"})
+
+	for _, want := range []string{"turn", "end"} {
+		select {
+		case event := <-events:
+			if event.Type != want {
+				t.Errorf("event.Type = %q, want %q", event.Type, want)
+			}
+		default:
+			t.Fatalf("missing %s event", want)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Errorf("received unexpected %s event", event.Type)
+	default:
+	}
+
+	unsubscribe()
+	if _, ok := hub.subscribers["battle"]; ok {
+		t.Error("subscriber still registered after unsubscribing")
+	}
+}
+
+func TestBattleEventHubDropsEventsForSlowSubscribers(t *testing.T) {
+	hub := newBattleEventHub()
+	events, unsubscribe := hub.subscribe("battle")
+	defer unsubscribe()
+
+	for i := 0; i < EventBufferSize+5; i++ {
+		hub.publish("battle", BattleEvent{Type: "turn"})
+	}
+	if len(events) != EventBufferSize {
+		t.Errorf("queued %d events, want %d", len(events), EventBufferSize)
+	}
+}
+
+func TestTurnEvents(t *testing.T) {
+	battle := testAIBattle()
+	battle.BattleId = "battle"
+	hpBefore := teamHP(battle)
+
+	battle.ComputerTeam[0].CurrentHP = 0
+	battle.BattleStatus = "won"
+	turnResult := &TurnResult{
+		StatusEvents: []TurnAction{{Action: "faint", Actor: "computer"}},
+		BattleEnded:  true,
+		Winner:       "player",
+	}
+
+	events := turnEvents(battle, hpBefore, turnResult)
+
+	var types []string
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	want := []string{"turn", "status", "hp", "end"}
+	if len(types) != len(want) {
+		t.Fatalf("event types = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("event types = %v, want %v", types, want)
+		}
+	}
+
+	hp := events[2].HP
+	if hp.Side != "computer" || hp.Index != 0 || hp.CurrentHP != 0 {
+		t.Errorf("hp event = %+v, want computer 0 at 0 HP", hp)
+	}
+	if events[3].Winner != "player" {
+		t.Errorf("end event winner = %q, want player", events[3].Winner)
+	}
+}
+
+func TestLoadStreamedBattle(t *testing.T) {
+	now := time.Now()
+	battle := &BattleState{BattleId: "streamed-battle", UserId: "owner", StreamTokens: map[string]StreamToken{
+		"player":   {Token: "player-token", ExpiresAt: now.Add(StreamTokenTTL).Format(time.RFC3339)},
+		"computer": {Token: "expired-token", ExpiresAt: now.Add(-time.Second).Format(time.RFC3339)},
+	}}
+	if err := getBattleStore().Save(battle); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		battleId string
+		token    string
+		wantSide string
+	}{
+		{name: "valid token", battleId: "streamed-battle", token: "player-token", wantSide: "player"},
+		{name: "expired token", battleId: "streamed-battle", token: "expired-token"},
+		{name: "wrong token", battleId: "streamed-battle", token: "not-the-token"},
+		{name: "missing token", battleId: "streamed-battle", token: ""},
+		{name: "unknown battle", battleId: "missing", token: "player-token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, side, err := loadStreamedBattle(tt.battleId, tt.token, now)
+			if tt.wantSide == "" {
+				if !errors.Is(err, ErrBattleNotFound) {
+					t.Errorf("loadStreamedBattle() error = %v, want ErrBattleNotFound", err)
+				}
+				return
+			}
+			if err != nil || side != tt.wantSide {
+				t.Errorf("loadStreamedBattle() = %q, %v, want %q", side, err, tt.wantSide)
+			}
+		})
+	}
+
+	if view := battle.viewFor("player"); view.StreamTokens != nil {
+		t.Error("viewFor exposes stream tokens")
+	}
+}
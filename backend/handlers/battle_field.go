@@ -0,0 +1,222 @@
+package handlers
+
+import "fmt"
+
+const (
+	WeatherRain      = "rain"
+	WeatherSun       = "sun"
+	WeatherSandstorm = "sandstorm"
+	WeatherHail      = "hail" // Also set by Snowscape and Snow Warning
+
+	TerrainElectric = "electric"
+	TerrainGrassy   = "grassy"
+	TerrainPsychic  = "psychic"
+	TerrainMisty    = "misty"
+
+	FieldDuration          = 5  // Turns weather and terrain last, including the one they're set on
+	WeatherDamageFraction  = 16 // Sandstorm and hail deal 1/16 of max HP each turn
+	GrassyTerrainFraction  = 16 // Grassy Terrain restores 1/16 of max HP each turn
+	WeatherBoostMultiplier = 1.5
+	WeatherDropMultiplier  = 0.5
+	TerrainBoostMultiplier = 1.3
+)
+
+// FieldState is the weather and terrain affecting both sides. Turns count down at
+// the end of each turn and the condition ends when they reach zero.
+type FieldState struct {
+	Weather      string `json:"weather,omitempty"` // "rain", "sun", "sandstorm" or "hail"
+	WeatherTurns int    `json:"weatherTurns,omitempty"`
+	Terrain      string `json:"terrain,omitempty"` // "electric", "grassy", "psychic" or "misty"
+	TerrainTurns int    `json:"terrainTurns,omitempty"`
+}
+
+// fieldCondition describes one kind of weather or terrain
+type fieldCondition struct {
+	started string // Battle log messages
+	ended   string
+	boosts  string // Move type powered up
+	weakens string // Move type powered down
+}
+
+var weatherConditions = map[string]fieldCondition{
+	WeatherRain:      {started: "It started to rain!", ended: "The rain stopped.", boosts: "water", weakens: "fire"},
+	WeatherSun:       {started: "The sunlight turned harsh!", ended: "The harsh sunlight faded.", boosts: "fire", weakens: "water"},
+	WeatherSandstorm: {started: "A sandstorm kicked up!", ended: "The sandstorm subsided."},
+	WeatherHail:      {started: "It started to hail!", ended: "The hail stopped."},
+}
+
+// Terrain only affects Pokemon on the ground. Misty Terrain weakens Dragon moves
+// used against them rather than boosting anything.
+var terrainConditions = map[string]fieldCondition{
+	TerrainElectric: {started: "An electric current ran across the battlefield!", ended: "The electricity disappeared from the battlefield.", boosts: "electric"},
+	TerrainGrassy:   {started: "Grass grew to cover the battlefield!", ended: "The grass disappeared from the battlefield.", boosts: "grass"},
+	TerrainPsychic:  {started: "The battlefield got weird!", ended: "The weirdness disappeared from the battlefield.", boosts: "psychic"},
+	TerrainMisty:    {started: "Mist swirled around the battlefield!", ended: "The mist disappeared from the battlefield.", weakens: "dragon"},
+}
+
+// weatherMoves and terrainMoves are the status moves that set the field
+var weatherMoves = map[string]string{
+	"rain-dance": WeatherRain,
+	"sunny-day":  WeatherSun,
+	"sandstorm":  WeatherSandstorm,
+	"hail":       WeatherHail,
+	"snowscape":  WeatherHail,
+}
+
+var terrainMoves = map[string]string{
+	"electric-terrain": TerrainElectric,
+	"grassy-terrain":   TerrainGrassy,
+	"psychic-terrain":  TerrainPsychic,
+	"misty-terrain":    TerrainMisty,
+}
+
+// weatherImmunities are the types that don't take damage from each weather
+var weatherImmunities = map[string][]string{
+	WeatherSandstorm: {"rock", "ground", "steel"},
+	WeatherHail:      {"ice"},
+}
+
+// setWeather starts a weather for FieldDuration turns and returns its message.
+// Returns "" if that weather is already active or there's no field.
+func (f *FieldState) setWeather(weather string) string {
+	if f == nil || f.Weather == weather {
+		return ""
+	}
+	f.Weather = weather
+	f.WeatherTurns = FieldDuration
+	return weatherConditions[weather].started
+}
+
+// setTerrain starts a terrain for FieldDuration turns and returns its message.
+// Returns "" if that terrain is already active or there's no field.
+func (f *FieldState) setTerrain(terrain string) string {
+	if f == nil || f.Terrain == terrain {
+		return ""
+	}
+	f.Terrain = terrain
+	f.TerrainTurns = FieldDuration
+	return terrainConditions[terrain].started
+}
+
+// applyMove sets the field for a weather or terrain move. ok is false if the move
+// doesn't change the field; message is "" if it failed, which it always does on a nil field.
+func (f *FieldState) applyMove(moveName string) (message string, ok bool) {
+	if weather, ok := weatherMoves[moveName]; ok {
+		return f.setWeather(weather), true
+	}
+	if terrain, ok := terrainMoves[moveName]; ok {
+		return f.setTerrain(terrain), true
+	}
+	return "", false
+}
+
+// isGrounded reports whether terrain affects a Pokemon
+func isGrounded(pokemon *BattlePokemon) bool {
+	return !hasType(pokemon, "flying") && pokemon.Ability != "levitate"
+}
+
+// damageModifier returns how weather and terrain scale a move's damage. A nil
+// field has no effect.
+func (f *FieldState) damageModifier(attacker, defender *BattlePokemon, move *PokemonMove) float64 {
+	if f == nil {
+		return 1
+	}
+
+	multiplier := 1.0
+	if weather, ok := weatherConditions[f.Weather]; ok {
+		switch move.Type {
+		case weather.boosts:
+			multiplier *= WeatherBoostMultiplier
+		case weather.weakens:
+			multiplier *= WeatherDropMultiplier
+		}
+	}
+	if terrain, ok := terrainConditions[f.Terrain]; ok {
+		if move.Type == terrain.boosts && isGrounded(attacker) {
+			multiplier *= TerrainBoostMultiplier
+		}
+		if move.Type == terrain.weakens && isGrounded(defender) {
+			multiplier *= WeatherDropMultiplier
+		}
+	}
+	return multiplier
+}
+
+// applyFieldEffects deals weather damage and Grassy Terrain healing to a side's
+// active Pokemon at the end of the turn
+func applyFieldEffects(battle *BattleState, actor string, turnNumber int, timestamp string) []*TurnAction {
+	pokemon := battle.activePokemon(actor)
+	if pokemon.CurrentHP <= 0 {
+		return nil
+	}
+
+	var actions []*TurnAction
+	if immune, ok := weatherImmunities[battle.Field.Weather]; ok && !hasAnyType(pokemon, immune) {
+		before := pokemon.CurrentHP
+		hurt(pokemon, pokemon.MaxHP/WeatherDamageFraction)
+		actions = append(actions, &TurnAction{
+			Turn:          turnNumber,
+			Actor:         actor,
+			Action:        "weather",
+			MoveName:      battle.Field.Weather,
+			Damage:        before - pokemon.CurrentHP,
+			Effectiveness: 1,
+			Message:       fmt.Sprintf("%s is buffeted by the %s! It lost %d HP!", pokemon.Name, battle.Field.Weather, before-pokemon.CurrentHP),
+			Timestamp:     timestamp,
+		})
+	}
+
+	if battle.Field.Terrain == TerrainGrassy && isGrounded(pokemon) && pokemon.CurrentHP > 0 && pokemon.CurrentHP < pokemon.MaxHP {
+		heal(pokemon, pokemon.MaxHP/GrassyTerrainFraction)
+		actions = append(actions, &TurnAction{
+			Turn:          turnNumber,
+			Actor:         actor,
+			Action:        "terrain",
+			MoveName:      TerrainGrassy,
+			Effectiveness: 1,
+			Message:       fmt.Sprintf("%s's HP was restored by the grassy terrain!", pokemon.Name),
+			Timestamp:     timestamp,
+		})
+	}
+	return actions
+}
+
+// tickField counts down the weather and terrain, returning an action for each that ended
+func tickField(battle *BattleState, turnNumber int, timestamp string) []*TurnAction {
+	var actions []*TurnAction
+	ended := func(action, name, message string) {
+		actions = append(actions, &TurnAction{
+			Turn:          turnNumber,
+			Action:        action,
+			MoveName:      name,
+			Effectiveness: 1,
+			Message:       message,
+			Timestamp:     timestamp,
+		})
+	}
+
+	field := &battle.Field
+	if field.Weather != "" {
+		if field.WeatherTurns--; field.WeatherTurns <= 0 {
+			ended("weather", field.Weather, weatherConditions[field.Weather].ended)
+			field.Weather, field.WeatherTurns = "", 0
+		}
+	}
+	if field.Terrain != "" {
+		if field.TerrainTurns--; field.TerrainTurns <= 0 {
+			ended("terrain", field.Terrain, terrainConditions[field.Terrain].ended)
+			field.Terrain, field.TerrainTurns = "", 0
+		}
+	}
+	return actions
+}
+
+// hasAnyType reports whether a Pokemon has at least one of the given types
+func hasAnyType(pokemon *BattlePokemon, types []string) bool {
+	for _, t := range types {
+		if hasType(pokemon, t) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import "testing"
+
+func TestFieldDamageModifier(t *testing.T) {
+	waterGun := &PokemonMove{Name: "water-gun", Type: "water"}
+	ember := &PokemonMove{Name: "ember", Type: "fire"}
+	thunderbolt := &PokemonMove{Name: "thunderbolt", Type: "electric"}
+	dragonPulse := &PokemonMove{Name: "dragon-pulse", Type: "dragon"}
+	grounded := &BattlePokemon{Types: []string{"normal"}}
+	flying := &BattlePokemon{Types: []string{"flying"}}
+
+	tests := []struct {
+		name     string
+		field    *FieldState
+		attacker *BattlePokemon
+		defender *BattlePokemon
+		move     *PokemonMove
+		want     float64
+	}{
+		{"no field", nil, grounded, grounded, waterGun, 1},
+		{"rain boosts water", &FieldState{Weather: WeatherRain}, grounded, grounded, waterGun, WeatherBoostMultiplier},
+		{"rain weakens fire", &FieldState{Weather: WeatherRain}, grounded, grounded, ember, WeatherDropMultiplier},
+		{"sun boosts fire", &FieldState{Weather: WeatherSun}, grounded, grounded, ember, WeatherBoostMultiplier},
+		{"sandstorm", &FieldState{Weather: WeatherSandstorm}, grounded, grounded, ember, 1},
+		{"electric terrain", &FieldState{Terrain: TerrainElectric}, grounded, grounded, thunderbolt, TerrainBoostMultiplier},
+		{"terrain skips flying attackers", &FieldState{Terrain: TerrainElectric}, flying, grounded, thunderbolt, 1},
+		{"misty terrain", &FieldState{Terrain: TerrainMisty}, flying, grounded, dragonPulse, WeatherDropMultiplier},
+		{"misty terrain skips flying targets", &FieldState{Terrain: TerrainMisty}, grounded, flying, dragonPulse, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.field.damageModifier(tt.attacker, tt.defender, tt.move); got != tt.want {
+				t.Errorf("damageModifier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWeatherMoveSetsField(t *testing.T) {
+	battle := testAIBattle()
+	battle.RNG = newBattleRNG(1)
+	rainDance := &PokemonMove{Name: "rain-dance", Type: "water", CurrentPP: 5, DamageClass: "status"}
+	attacker := battle.activePokemon("computer")
+
+	action := executeMove(battle.random().Rand, &battle.Field, attacker, battle.activePokemon("player"), rainDance, "computer", 1, "")
+	if battle.Field.Weather != WeatherRain || battle.Field.WeatherTurns != FieldDuration {
+		t.Fatalf("field = %+v, want %d turns of rain", battle.Field, FieldDuration)
+	}
+	if action.Message != "squirtle used rain-dance! It started to rain!" {
+		t.Errorf("message = %q", action.Message)
+	}
+
+	action = executeMove(battle.random().Rand, &battle.Field, attacker, battle.activePokemon("player"), rainDance, "computer", 2, "")
+	if action.Message != "squirtle used rain-dance! But it failed!" {
+		t.Errorf("message = %q, want rain dance to fail while it's raining", action.Message)
+	}
+
+	action = executeMove(battle.random().Rand, nil, attacker, battle.activePokemon("player"), rainDance, "computer", 3, "")
+	if action.Message != "squirtle used rain-dance! But it failed!" {
+		t.Errorf("message = %q, want rain dance to fail without a field", action.Message)
+	}
+}
+
+func TestApplyFieldEffects(t *testing.T) {
+	battle := testAIBattle()
+	battle.Field = FieldState{Weather: WeatherSandstorm, WeatherTurns: 3, Terrain: TerrainGrassy, TerrainTurns: 1}
+	battle.PlayerTeam[0].Types = []string{"fire", "flying"}
+	battle.ComputerTeam[0].Types = []string{"rock"}
+	battle.ComputerTeam[0].CurrentHP = 100
+
+	actions := applyFieldEffects(battle, "player", 1, "")
+	if len(actions) != 1 || actions[0].Action != "weather" || actions[0].Damage != 150/WeatherDamageFraction {
+		t.Errorf("player actions = %+v, want sandstorm damage only", actions)
+	}
+
+	actions = applyFieldEffects(battle, "computer", 1, "")
+	if len(actions) != 1 || actions[0].Action != "terrain" {
+		t.Errorf("computer actions = %+v, want a grassy terrain heal only", actions)
+	}
+
+	ended := tickField(battle, 1, "")
+	if len(ended) != 1 || ended[0].MoveName != TerrainGrassy {
+		t.Errorf("ended = %+v, want grassy terrain to end", ended)
+	}
+	if battle.Field != (FieldState{Weather: WeatherSandstorm, WeatherTurns: 2}) {
+		t.Errorf("field = %+v, want 2 turns of sandstorm left", battle.Field)
+	}
+}
+
+func TestWeatherAbilityOnLead(t *testing.T) {
+	battle := testAIBattle()
+	battle.ComputerTeam[0].Ability = "drought"
+
+	applyLeadEffects(battle, "")
+	if battle.Field.Weather != WeatherSun {
+		t.Errorf("weather = %q, want sun", battle.Field.Weather)
+	}
+}
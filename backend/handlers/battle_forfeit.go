@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"backend/config"
+)
+
+// forfeitMessages are the battle log messages for each way of giving up a battle.
+// "timeout" is only ever chosen by the server, never accepted from a request.
+var forfeitMessages = map[string]string{
+	"forfeit": "%s forfeited the battle!",
+	"run":     "%s got away safely!",
+	"timeout": "%s ran out of time and forfeited!",
+}
+
+// isForfeit reports whether a choice gives up the battle
+func isForfeit(choice BattleChoice) bool {
+	_, ok := forfeitMessages[choice.Action]
+	return ok
+}
+
+// winningSide returns the side that won a finished battle, or "" while it's undecided
+func (b *BattleState) winningSide() string {
+	switch b.BattleStatus {
+	case "won":
+		return "player"
+	case "lost":
+		return "computer"
+	case "forfeited", "timed-out":
+		return opponentOf(b.ForfeitedBy)
+	default:
+		return ""
+	}
+}
+
+// finished reports whether the battle is over, however it ended
+func (b *BattleState) finished() bool {
+	return b.winningSide() != ""
+}
+
+// forfeitBattle ends the battle in the opponent's favour. action is "forfeit", "run"
+// or "timeout", and is recorded as the side's choice so replays end the same way.
+func forfeitBattle(battle *BattleState, actor, action string) *TurnResult {
+	turnNumber := nextTurnNumber(battle)
+	battle.Choices = append(battle.Choices, ReplayTurn{
+		Turn:    turnNumber,
+		Choices: map[string]BattleChoice{actor: {Action: action}},
+	})
+
+	winner := opponentOf(actor)
+	battle.BattleStatus = "forfeited"
+	if action == "timeout" {
+		battle.BattleStatus = "timed-out"
+	}
+	battle.EndReason = action
+	battle.ForfeitedBy = actor
+	battle.WinnerUserId = battle.UserId
+	if winner == "computer" {
+		battle.WinnerUserId = battle.OpponentUserId
+	}
+	battle.CurrentTurn = "finished"
+	battle.PendingChoices = nil
+	battle.ForcedSwitches = nil
+	battle.TurnDeadline = ""
+
+	turnResult := &TurnResult{BattleEnded: true, Winner: winner}
+	recordStatusEvent(battle, turnResult, &TurnAction{
+		Turn:      turnNumber - 1, // The turn wasn't played, so it's logged with the last one
+		Actor:     actor,
+		Action:    action,
+		Message:   fmt.Sprintf(forfeitMessages[action], battle.trainerName(actor)),
+		Timestamp: time.Now().Format(time.RFC3339),
+	})
+	return turnResult
+}
+
+// startTurnClock sets the deadline for the sides that now have to choose. Turn
+// timeouts are disabled when BATTLE_TURN_TIMEOUT is 0.
+func (b *BattleState) startTurnClock(now time.Time) {
+	timeout := config.LoadBattleTimeoutConfig().TurnTimeout
+	if timeout <= 0 || b.BattleStatus != "active" {
+		b.TurnDeadline = ""
+		return
+	}
+	b.TurnDeadline = now.Add(timeout).Format(time.RFC3339)
+}
+
+// lateSides returns the user-controlled sides that still have to choose this turn
+func (b *BattleState) lateSides() []string {
+	var sides []string
+	for _, side := range b.choosingSides() {
+		if _, chosen := b.PendingChoices[side]; b.controlledByUser(side) && !chosen {
+			sides = append(sides, side)
+		}
+	}
+	return sides
+}
+
+// autoPick chooses for a side that ran out of time: the first healthy Pokemon when it
+// has to switch, otherwise the move with the highest expected damage. It doesn't use
+// the battle's RNG, so the picked choice replays the same way as a chosen one.
+func autoPick(battle *BattleState, actor string) BattleChoice {
+	if battle.CurrentTurn == "switch" {
+		return BattleChoice{Action: "switch", SwitchTo: nextAvailablePokemon(battle.team(actor))}
+	}
+	return greedyStrategy{}.ChooseAction(battle, actor)
+}
+
+// timeOutTurn applies the turn timeout if the deadline passed before now. Sides that
+// haven't chosen forfeit, or with auto-pick have their choice made for them until
+// they've missed MaxAutoPicks turns in a row. Returns nil if nothing was due.
+func timeOutTurn(battle *BattleState, now time.Time, timeoutConfig config.BattleTimeoutConfig) *TurnResult {
+	if battle.BattleStatus != "active" || battle.TurnDeadline == "" || timeoutConfig.TurnTimeout <= 0 {
+		return nil
+	}
+	deadline, err := time.Parse(time.RFC3339, battle.TurnDeadline)
+	if err != nil || now.Before(deadline) {
+		return nil
+	}
+
+	var turnResult *TurnResult
+	for _, side := range battle.lateSides() {
+		if battle.TimeoutStrikes == nil {
+			battle.TimeoutStrikes = make(map[string]int)
+		}
+		battle.TimeoutStrikes[side]++
+		if timeoutConfig.Action == "forfeit" || battle.TimeoutStrikes[side] > timeoutConfig.MaxAutoPicks {
+			return forfeitBattle(battle, side, "timeout")
+		}
+
+		choice := autoPick(battle, side)
+		if battle.isPvP() {
+			turnResult, err = submitChoice(battle, side, choice)
+		} else {
+			turnResult, err = processBattleTurn(battle, choice)
+		}
+		if err != nil {
+			log.Printf("Error auto-picking for %s in battle %s: %v", side, battle.BattleId, err)
+			return forfeitBattle(battle, side, "timeout")
+		}
+	}
+
+	// Time missed while nobody was looking counts against the next turn too
+	battle.TurnDeadline = ""
+	if battle.BattleStatus == "active" {
+		battle.TurnDeadline = deadline.Add(timeoutConfig.TurnTimeout).Format(time.RFC3339)
+	}
+	return turnResult
+}
+
+// enforceTurnTimeout applies every turn timeout that's due, saves the battle and
+// notifies anyone streaming it. If another request saved the battle first it's
+// reloaded instead, since that request has applied the timeouts already.
+func enforceTurnTimeout(battle *BattleState) (*BattleState, error) {
+	timeoutConfig := config.LoadBattleTimeoutConfig()
+	now := time.Now()
+
+	var events []BattleEvent
+	for {
+		hpBefore := teamHP(battle)
+		turnResult := timeOutTurn(battle, now, timeoutConfig)
+		if turnResult == nil {
+			break
+		}
+		events = append(events, turnEvents(battle, hpBefore, turnResult)...)
+	}
+	if len(events) == 0 {
+		return battle, nil
+	}
+
+	battle.UpdatedAt = now.Format(time.RFC3339)
+	if err := saveBattleState(battle); err != nil {
+		if errors.Is(err, ErrBattleConflict) {
+			return getBattleStore().Load(battle.BattleId)
+		}
+		return nil, err
+	}
+
+	log.Printf("Applied turn timeout to battle %s", battle.BattleId)
+	battleEvents.publish(battle.BattleId, events...)
+	if battle.finished() {
+		onBattleFinished(battle)
+	}
+	return battle, nil
+}
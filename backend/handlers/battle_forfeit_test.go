@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"backend/config"
+)
+
+func TestForfeitComputerBattle(t *testing.T) {
+	battle := testAIBattle()
+	battle.UserId = "user-1"
+	battle.CurrentTurn = "player"
+	battle.BattleStatus = "active"
+
+	turnResult, err := processBattleTurn(battle, BattleChoice{Action: "run"})
+	if err != nil {
+		t.Fatalf("processBattleTurn() error = %v", err)
+	}
+
+	if !turnResult.BattleEnded || turnResult.Winner != "computer" {
+		t.Errorf("turn result = %+v, want the computer to win", turnResult)
+	}
+	if battle.BattleStatus != "forfeited" || battle.EndReason != "run" || battle.ForfeitedBy != "player" {
+		t.Errorf("status = %s, reason = %s, forfeited by %s, want forfeited, run and player", battle.BattleStatus, battle.EndReason, battle.ForfeitedBy)
+	}
+	if battle.winningSide() != "computer" || battle.WinnerUserId != "" {
+		t.Errorf("winning side = %s, winner user = %q, want computer and no user", battle.winningSide(), battle.WinnerUserId)
+	}
+	if last := battle.TurnHistory[len(battle.TurnHistory)-1]; last.Action != "run" || last.Message != "You got away safely!" {
+		t.Errorf("last action = %+v, want the player running away", last)
+	}
+}
+
+func TestForfeitPvPBattle(t *testing.T) {
+	battle := testPvPBattle()
+	battle.PlayerName, battle.OpponentName = "ash", "gary"
+	battle.InitialPlayerTeam = cloneTeam(battle.PlayerTeam)
+	battle.InitialComputerTeam = cloneTeam(battle.ComputerTeam)
+
+	if _, err := submitChoice(battle, "player", BattleChoice{Action: "run"}); err == nil {
+		t.Error("submitChoice() allowed running from a PvP battle")
+	}
+	if _, err := submitChoice(battle, "computer", BattleChoice{Action: "attack", MoveName: "tackle"}); err != nil {
+		t.Fatalf("submitChoice() error = %v", err)
+	}
+
+	// The opponent already chose, but the player can still give up
+	turnResult, err := submitChoice(battle, "player", BattleChoice{Action: "forfeit"})
+	if err != nil {
+		t.Fatalf("submitChoice() error = %v", err)
+	}
+	if turnResult == nil || turnResult.Winner != "computer" {
+		t.Fatalf("turn result = %+v, want the opponent to win", turnResult)
+	}
+	if battle.WinnerUserId != "user-2" || battle.PendingChoices != nil {
+		t.Errorf("winner = %s, pending = %v, want user-2 and no pending choices", battle.WinnerUserId, battle.PendingChoices)
+	}
+
+	summary := summarizeBattle(battle, "computer")
+	if summary.Result != "won" || summary.EndReason != "forfeit" || summary.ForfeitedBy != "ash" {
+		t.Errorf("summary = %+v, want a win after ash forfeited", summary)
+	}
+
+	replayed, err := simulateReplay(exportReplay(battle))
+	if err != nil {
+		t.Fatalf("simulateReplay() error = %v", err)
+	}
+	if replayed.BattleStatus != "forfeited" || replayed.ForfeitedBy != "player" {
+		t.Errorf("replayed status = %s, forfeited by %s, want forfeited by player", replayed.BattleStatus, replayed.ForfeitedBy)
+	}
+}
+
+func TestTimeOutTurn(t *testing.T) {
+	deadline := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	autoPick := config.BattleTimeoutConfig{TurnTimeout: time.Minute, Action: "auto-pick", MaxAutoPicks: 1}
+
+	tests := []struct {
+		name          string
+		now           time.Time
+		timeoutConfig config.BattleTimeoutConfig
+		strikes       int
+		wantResult    bool
+		wantStatus    string
+	}{
+		{name: "before the deadline", now: deadline.Add(-time.Second), timeoutConfig: autoPick, wantStatus: "active"},
+		{name: "timeouts disabled", now: deadline.Add(time.Hour), timeoutConfig: config.BattleTimeoutConfig{}, wantStatus: "active"},
+		{name: "auto-pick", now: deadline, timeoutConfig: autoPick, wantResult: true, wantStatus: "active"},
+		{name: "too many auto-picks", now: deadline, timeoutConfig: autoPick, strikes: 1, wantResult: true, wantStatus: "timed-out"},
+		{
+			name:          "forfeit",
+			now:           deadline,
+			timeoutConfig: config.BattleTimeoutConfig{TurnTimeout: time.Minute, Action: "forfeit"},
+			wantResult:    true,
+			wantStatus:    "timed-out",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			battle := testPvPBattle()
+			battle.ComputerTeam[0].CurrentHP = 1000
+			battle.ComputerTeam[0].MaxHP = 1000
+			battle.TurnDeadline = deadline.Format(time.RFC3339)
+			battle.TimeoutStrikes = map[string]int{"player": tt.strikes}
+			if _, err := submitChoice(battle, "computer", BattleChoice{Action: "attack", MoveName: "tackle"}); err != nil {
+				t.Fatalf("submitChoice() error = %v", err)
+			}
+
+			turnResult := timeOutTurn(battle, tt.now, tt.timeoutConfig)
+			if (turnResult != nil) != tt.wantResult {
+				t.Fatalf("timeOutTurn() = %+v, want a result: %v", turnResult, tt.wantResult)
+			}
+			if battle.BattleStatus != tt.wantStatus {
+				t.Errorf("BattleStatus = %s, want %s", battle.BattleStatus, tt.wantStatus)
+			}
+
+			switch tt.wantStatus {
+			case "timed-out":
+				if battle.ForfeitedBy != "player" || battle.WinnerUserId != "user-2" {
+					t.Errorf("forfeited by %s, winner %s, want player and user-2", battle.ForfeitedBy, battle.WinnerUserId)
+				}
+			case "active":
+				if !tt.wantResult {
+					break
+				}
+				if turnResult.PlayerAction == nil || turnResult.PlayerAction.MoveName != "ember" {
+					t.Errorf("player action = %+v, want an auto-picked ember", turnResult.PlayerAction)
+				}
+				if want := deadline.Add(time.Minute).Format(time.RFC3339); battle.TurnDeadline != want {
+					t.Errorf("TurnDeadline = %s, want %s", battle.TurnDeadline, want)
+				}
+			}
+		})
+	}
+}
+
+func TestComputerBattleNotTimedOutByDefault(t *testing.T) {
+	t.Setenv("BATTLE_TURN_TIMEOUT", "")
+	now := time.Now()
+
+	battle := testAIBattle()
+	battle.BattleId = "default-timeout-battle"
+	battle.UserId = "user-1"
+	battle.CurrentTurn = "player"
+	battle.BattleStatus = "active"
+	battle.startTurnClock(now)
+	if battle.TurnDeadline != "" {
+		t.Errorf("TurnDeadline = %s, want no deadline", battle.TurnDeadline)
+	}
+
+	// A deadline left over from when timeouts were enabled is ignored too
+	battle.TurnDeadline = now.Add(-time.Hour).Format(time.RFC3339)
+	if err := getBattleStore().Save(battle); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := enforceTurnTimeout(battle)
+	if err != nil {
+		t.Fatalf("enforceTurnTimeout() error = %v", err)
+	}
+	if loaded.BattleStatus != "active" || len(loaded.TurnHistory) != 0 || loaded.TimeoutStrikes["player"] != 0 {
+		t.Errorf("battle = %s with %d turns and %d strikes, want it untouched", loaded.BattleStatus, len(loaded.TurnHistory), loaded.TimeoutStrikes["player"])
+	}
+}
@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"backend/config"
+	"backend/middleware"
+)
+
+const (
+	DefaultHistoryPageSize = 20
+	MaxHistoryPageSize     = 100
+)
+
+// ErrInvalidCursor is returned when a history or leaderboard page cursor can't be decoded
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// BattleSummary is the permanent record of a finished battle from one user's point of view
+type BattleSummary struct {
+	UserId       string   `json:"userId" dynamodbav:"userId"`
+	SortKey      string   `json:"-" dynamodbav:"sortKey"` // EndedAt#BattleId, orders a user's battles by time
+	BattleId     string   `json:"battleId" dynamodbav:"battleId"`
+	Mode         string   `json:"mode" dynamodbav:"mode"`
+	Opponent     string   `json:"opponent" dynamodbav:"opponent"`         // Opponent's username, or "Computer"
+	OpponentType string   `json:"opponentType" dynamodbav:"opponentType"` // "pvp" or "computer-{difficulty}"
+	Team         []string `json:"team" dynamodbav:"team"`
+	OpponentTeam []string `json:"opponentTeam" dynamodbav:"opponentTeam"`
+	Turns        int      `json:"turns" dynamodbav:"turns"`
+	Result       string   `json:"result" dynamodbav:"result"`                     // "won" or "lost"
+	EndReason    string   `json:"endReason,omitempty" dynamodbav:"endReason"`     // "knockout", "forfeit", "run" or "timeout"
+	ForfeitedBy  string   `json:"forfeitedBy,omitempty" dynamodbav:"forfeitedBy"` // Username of whoever forfeited, ran or timed out
+	EndedAt      string   `json:"endedAt" dynamodbav:"endedAt"`
+}
+
+// BattleHistoryStore keeps battle summaries after the battles themselves expire
+type BattleHistoryStore interface {
+	Record(summary BattleSummary) error
+	// List returns up to limit summaries, newest first, starting after cursor.
+	// The returned cursor is empty on the last page.
+	List(userId string, limit int, cursor string) ([]BattleSummary, string, error)
+}
+
+type BattleHistoryResponse struct {
+	Battles    []BattleSummary `json:"battles,omitempty"`
+	NextCursor string          `json:"nextCursor,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+// WinRate aggregates results for one Pokemon or opponent type
+type WinRate struct {
+	Key     string  `json:"key"`
+	Battles int     `json:"battles"`
+	Wins    int     `json:"wins"`
+	WinRate float64 `json:"winRate"` // 0-1
+}
+
+type BattleStats struct {
+	Battles        int       `json:"battles"`
+	Wins           int       `json:"wins"`
+	WinRate        float64   `json:"winRate"`
+	ByPokemon      []WinRate `json:"byPokemon"`
+	ByOpponentType []WinRate `json:"byOpponentType"`
+}
+
+type BattleStatsResponse struct {
+	Stats *BattleStats `json:"stats,omitempty"`
+	Error string       `json:"error,omitempty"`
+}
+
+var (
+	battleHistoryStore     BattleHistoryStore
+	battleHistoryStoreOnce sync.Once
+)
+
+// getBattleHistoryStore returns the configured history store, using the same
+// backend as battle storage
+func getBattleHistoryStore() BattleHistoryStore {
+	battleHistoryStoreOnce.Do(func() {
+		storageConfig := config.LoadBattleStorageConfig()
+
+		if storageConfig.Backend == "dynamodb" {
+			store, err := newDynamoBattleHistoryStore(storageConfig.HistoryTableName)
+			if err == nil {
+				battleHistoryStore = store
+				return
+			}
+			log.Fatalf("Error creating DynamoDB battle history store: %v", err)
+		}
+
+		battleHistoryStore = newMemoryBattleHistoryStore()
+	})
+	return battleHistoryStore
+}
+
+// summarizeBattle builds a finished battle's summary for the user controlling side
+func summarizeBattle(battle *BattleState, side string) BattleSummary {
+	opponentSide := opponentOf(side)
+
+	userId, opponent, opponentType := battle.UserId, "Computer", "computer-"+battle.Difficulty
+	if battle.isPvP() {
+		opponent, opponentType = battle.OpponentName, "pvp"
+		if side == "computer" {
+			userId, opponent = battle.OpponentUserId, battle.PlayerName
+		}
+	}
+
+	result := "lost"
+	if battle.winningSide() == side {
+		result = "won"
+	}
+
+	// Name whoever gave up, so the history can show who forfeited and why
+	forfeitedBy := ""
+	switch {
+	case battle.ForfeitedBy == "player":
+		forfeitedBy = battle.PlayerName
+	case battle.ForfeitedBy == "computer" && battle.isPvP():
+		forfeitedBy = battle.OpponentName
+	}
+
+	turns := 0
+	if len(battle.TurnHistory) > 0 {
+		turns = battle.TurnHistory[len(battle.TurnHistory)-1].Turn
+	}
+
+	return BattleSummary{
+		UserId:       userId,
+		SortKey:      battle.UpdatedAt + "#" + battle.BattleId,
+		BattleId:     battle.BattleId,
+		Mode:         battle.Mode,
+		Opponent:     opponent,
+		OpponentType: opponentType,
+		Team:         pokemonNames(battle.team(side)),
+		OpponentTeam: pokemonNames(battle.team(opponentSide)),
+		Turns:        turns,
+		Result:       result,
+		EndReason:    battle.EndReason,
+		ForfeitedBy:  forfeitedBy,
+		EndedAt:      battle.UpdatedAt,
+	}
+}
+
+// pokemonNames lists the names of a team's Pokemon
+func pokemonNames(team []BattlePokemon) []string {
+	names := make([]string, len(team))
+	for i, pokemon := range team {
+		names[i] = pokemon.Name
+	}
+	return names
+}
+
+// recordBattleHistory saves a summary of a finished battle for each user in it
+func recordBattleHistory(battle *BattleState) {
+	sides := []string{"player"}
+	if battle.isPvP() {
+		sides = append(sides, "computer")
+	}
+
+	for _, side := range sides {
+		if err := getBattleHistoryStore().Record(summarizeBattle(battle, side)); err != nil {
+			log.Printf("Error recording battle history for %s: %v", battle.BattleId, err)
+		}
+	}
+}
+
+// listAllSummaries reads every page of a user's battle history
+func listAllSummaries(store BattleHistoryStore, userId string) ([]BattleSummary, error) {
+	var summaries []BattleSummary
+	cursor := ""
+	for {
+		page, next, err := store.List(userId, MaxHistoryPageSize, cursor)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, page...)
+		if next == "" {
+			return summaries, nil
+		}
+		cursor = next
+	}
+}
+
+// aggregateBattleStats computes overall, per-Pokemon and per-opponent-type win rates
+func aggregateBattleStats(summaries []BattleSummary) *BattleStats {
+	stats := &BattleStats{ByPokemon: []WinRate{}, ByOpponentType: []WinRate{}}
+	byPokemon := make(map[string]*WinRate)
+	byOpponentType := make(map[string]*WinRate)
+
+	count := func(rates map[string]*WinRate, key string, won bool) {
+		if rates[key] == nil {
+			rates[key] = &WinRate{Key: key}
+		}
+		rates[key].Battles++
+		if won {
+			rates[key].Wins++
+		}
+	}
+
+	for _, summary := range summaries {
+		won := summary.Result == "won"
+		stats.Battles++
+		if won {
+			stats.Wins++
+		}
+
+		// A Pokemon brought twice in one team still counts as one battle
+		seen := make(map[string]bool)
+		for _, name := range summary.Team {
+			if !seen[name] {
+				seen[name] = true
+				count(byPokemon, name, won)
+			}
+		}
+		count(byOpponentType, summary.OpponentType, won)
+	}
+
+	if stats.Battles > 0 {
+		stats.WinRate = float64(stats.Wins) / float64(stats.Battles)
+	}
+	stats.ByPokemon = sortedWinRates(byPokemon)
+	stats.ByOpponentType = sortedWinRates(byOpponentType)
+	return stats
+}
+
+// sortedWinRates fills in the rates and orders them by battles played, then key
+func sortedWinRates(rates map[string]*WinRate) []WinRate {
+	sorted := make([]WinRate, 0, len(rates))
+	for _, rate := range rates {
+		rate.WinRate = float64(rate.Wins) / float64(rate.Battles)
+		sorted = append(sorted, *rate)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Battles != sorted[j].Battles {
+			return sorted[i].Battles > sorted[j].Battles
+		}
+		return sorted[i].Key < sorted[j].Key
+	})
+	return sorted
+}
+
+// encodeHistoryCursor and decodeHistoryCursor turn a summary's sort key into an opaque page cursor
+func encodeHistoryCursor(sortKey string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(sortKey))
+}
+
+func decodeHistoryCursor(cursor string) (string, error) {
+	sortKey, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", ErrInvalidCursor
+	}
+	return string(sortKey), nil
+}
+
+// memoryBattleHistoryStore keeps battle summaries in memory. History is lost on restart.
+type memoryBattleHistoryStore struct {
+	summaries map[string][]BattleSummary // Per user, newest first
+	mutex     *sync.RWMutex
+}
+
+func newMemoryBattleHistoryStore() *memoryBattleHistoryStore {
+	return &memoryBattleHistoryStore{
+		summaries: make(map[string][]BattleSummary),
+		mutex:     &sync.RWMutex{},
+	}
+}
+
+func (s *memoryBattleHistoryStore) Record(summary BattleSummary) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	summaries := s.summaries[summary.UserId]
+	for i := range summaries {
+		if summaries[i].SortKey == summary.SortKey {
+			summaries[i] = summary
+			return nil
+		}
+	}
+
+	summaries = append(summaries, summary)
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].SortKey > summaries[j].SortKey
+	})
+	s.summaries[summary.UserId] = summaries
+	return nil
+}
+
+func (s *memoryBattleHistoryStore) List(userId string, limit int, cursor string) ([]BattleSummary, string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	after := ""
+	if cursor != "" {
+		var err error
+		if after, err = decodeHistoryCursor(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	var page []BattleSummary
+	for _, summary := range s.summaries[userId] {
+		if after != "" && summary.SortKey >= after {
+			continue
+		}
+		if len(page) == limit {
+			return page, encodeHistoryCursor(page[len(page)-1].SortKey), nil
+		}
+		page = append(page, summary)
+	}
+	return page, "", nil
+}
+
+// BattleHistoryHandler lists the user's finished battles, newest first.
+// Query parameters: limit (default 20, max 100) and cursor from the previous page.
+func BattleHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(BattleHistoryResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(BattleHistoryResponse{Error: "Authentication required"})
+		return
+	}
+
+	limit := DefaultHistoryPageSize
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 || parsed > MaxHistoryPageSize {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(BattleHistoryResponse{Error: fmt.Sprintf("Limit must be between 1 and %d", MaxHistoryPageSize)})
+			return
+		}
+		limit = parsed
+	}
+
+	battles, nextCursor, err := getBattleHistoryStore().List(user.Sub, limit, r.URL.Query().Get("cursor"))
+	if err != nil {
+		log.Printf("Error listing battle history: %v", err)
+		if errors.Is(err, ErrInvalidCursor) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(BattleHistoryResponse{Error: "Invalid cursor"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(BattleHistoryResponse{Error: "Failed to list battle history"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BattleHistoryResponse{Battles: battles, NextCursor: nextCursor})
+}
+
+// BattleStatsHandler returns the user's win rates overall, per Pokemon and per opponent type
+func BattleStatsHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(BattleStatsResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(BattleStatsResponse{Error: "Authentication required"})
+		return
+	}
+
+	summaries, err := listAllSummaries(getBattleHistoryStore(), user.Sub)
+	if err != nil {
+		log.Printf("Error loading battle history: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(BattleStatsResponse{Error: "Failed to load battle history"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BattleStatsResponse{Stats: aggregateBattleStats(summaries)})
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// dynamoBattleHistoryStore keeps battle summaries in a table keyed by userId and sortKey
+type dynamoBattleHistoryStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+func newDynamoBattleHistoryStore(tableName string) (*dynamoBattleHistoryStore, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &dynamoBattleHistoryStore{
+		client:    dynamodb.NewFromConfig(cfg),
+		tableName: tableName,
+	}, nil
+}
+
+func (s *dynamoBattleHistoryStore) Record(summary BattleSummary) error {
+	item, err := attributevalue.MarshalMap(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal battle summary: %w", err)
+	}
+
+	_, err = s.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName: &s.tableName,
+		Item:      item,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save battle summary: %w", err)
+	}
+	return nil
+}
+
+func (s *dynamoBattleHistoryStore) List(userId string, limit int, cursor string) ([]BattleSummary, string, error) {
+	limit32 := int32(limit)
+	input := &dynamodb.QueryInput{
+		TableName:              &s.tableName,
+		KeyConditionExpression: stringPtr("userId = :userId"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":userId": &types.AttributeValueMemberS{Value: userId},
+		},
+		ScanIndexForward: boolPtr(false), // Newest first
+		Limit:            &limit32,
+	}
+
+	if cursor != "" {
+		sortKey, err := decodeHistoryCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = map[string]types.AttributeValue{
+			"userId":  &types.AttributeValueMemberS{Value: userId},
+			"sortKey": &types.AttributeValueMemberS{Value: sortKey},
+		}
+	}
+
+	result, err := s.client.Query(context.TODO(), input)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query battle history: %w", err)
+	}
+
+	var summaries []BattleSummary
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &summaries); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal battle history: %w", err)
+	}
+
+	next := ""
+	if sortKey, ok := result.LastEvaluatedKey["sortKey"].(*types.AttributeValueMemberS); ok {
+		next = encodeHistoryCursor(sortKey.Value)
+	}
+	return summaries, next, nil
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestSummarizeBattle(t *testing.T) {
+	battle := testPvPBattle()
+	battle.BattleId = "battle"
+	battle.PlayerName = "ash"
+	battle.OpponentName = "gary"
+	battle.BattleStatus = "lost"
+	battle.UpdatedAt = "2024-01-02T03:04:05Z"
+	battle.TurnHistory = []TurnAction{{Turn: 1}, {Turn: 2}, {Turn: 2}}
+
+	tests := []struct {
+		side         string
+		wantUser     string
+		wantOpponent string
+		wantResult   string
+		wantTeam     string
+	}{
+		{side: "player", wantUser: "user-1", wantOpponent: "gary", wantResult: "lost", wantTeam: "charmander"},
+		{side: "computer", wantUser: "user-2", wantOpponent: "ash", wantResult: "won", wantTeam: "squirtle"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.side, func(t *testing.T) {
+			summary := summarizeBattle(battle, tt.side)
+			if summary.UserId != tt.wantUser || summary.Opponent != tt.wantOpponent || summary.Result != tt.wantResult {
+				t.Errorf("summary = %+v, want user %s vs %s, %s", summary, tt.wantUser, tt.wantOpponent, tt.wantResult)
+			}
+			if len(summary.Team) != 1 || summary.Team[0] != tt.wantTeam {
+				t.Errorf("Team = %v, want [%s]", summary.Team, tt.wantTeam)
+			}
+			if summary.Turns != 2 || summary.OpponentType != "pvp" {
+				t.Errorf("Turns = %d, OpponentType = %q, want 2 and pvp", summary.Turns, summary.OpponentType)
+			}
+		})
+	}
+}
+
+func TestMemoryBattleHistoryStorePagination(t *testing.T) {
+	store := newMemoryBattleHistoryStore()
+	for i := 0; i < 5; i++ {
+		endedAt := fmt.Sprintf("2024-01-0%dT00:00:00Z", i+1)
+		summary := BattleSummary{UserId: "user", BattleId: fmt.Sprintf("battle-%d", i), EndedAt: endedAt, SortKey: endedAt + "#battle"}
+		if err := store.Record(summary); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	store.Record(BattleSummary{UserId: "someone-else", SortKey: "x"})
+
+	var ids []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		page, next, err := store.List("user", 2, cursor)
+		if err != nil {
+			t.Fatalf("List() error = %v", err)
+		}
+		for _, summary := range page {
+			ids = append(ids, summary.BattleId)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	want := []string{"battle-4", "battle-3", "battle-2", "battle-1", "battle-0"}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("listed %v, want %v newest first", ids, want)
+	}
+
+	if _, _, err := store.List("user", 2, "not base64!"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("List() error = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestAggregateBattleStats(t *testing.T) {
+	summaries := []BattleSummary{
+		{Team: []string{"pikachu", "eevee"}, OpponentType: "computer-easy", Result: "won"},
+		{Team: []string{"pikachu"}, OpponentType: "computer-easy", Result: "lost"},
+		{Team: []string{"pikachu", "pikachu"}, OpponentType: "pvp", Result: "won"},
+	}
+
+	stats := aggregateBattleStats(summaries)
+
+	if stats.Battles != 3 || stats.Wins != 2 {
+		t.Errorf("Battles = %d, Wins = %d, want 3 and 2", stats.Battles, stats.Wins)
+	}
+	if len(stats.ByPokemon) != 2 || stats.ByPokemon[0] != (WinRate{Key: "pikachu", Battles: 3, Wins: 2, WinRate: 2.0 / 3}) {
+		t.Errorf("ByPokemon = %+v, want pikachu first with 2 of 3", stats.ByPokemon)
+	}
+	if stats.ByOpponentType[0] != (WinRate{Key: "computer-easy", Battles: 2, Wins: 1, WinRate: 0.5}) {
+		t.Errorf("ByOpponentType[0] = %+v, want computer-easy with 1 of 2", stats.ByOpponentType[0])
+	}
+
+	if empty := aggregateBattleStats(nil); empty.WinRate != 0 || len(empty.ByPokemon) != 0 {
+		t.Errorf("aggregateBattleStats(nil) = %+v, want zero stats", empty)
+	}
+}
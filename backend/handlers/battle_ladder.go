@@ -0,0 +1,469 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"backend/config"
+	"backend/middleware"
+)
+
+const (
+	DefaultRating          = 1500
+	MaxRatingHistory       = 50 // Rating changes kept per player and ladder
+	DefaultLeaderboardSize = 20
+	MaxLeaderboardSize     = 100
+	LadderPvP              = "pvp"
+)
+
+// computerRatings are the fixed ratings of the computer on each difficulty's ladder
+var computerRatings = map[string]int{
+	"easy":   1200,
+	"normal": 1500,
+	"hard":   1800,
+}
+
+// RatingChange records how one battle moved a player's rating
+type RatingChange struct {
+	BattleId       string `json:"battleId" dynamodbav:"battleId"`
+	Opponent       string `json:"opponent" dynamodbav:"opponent"`
+	OpponentRating int    `json:"opponentRating" dynamodbav:"opponentRating"`
+	Result         string `json:"result" dynamodbav:"result"` // "won" or "lost"
+	Before         int    `json:"before" dynamodbav:"before"`
+	After          int    `json:"after" dynamodbav:"after"`
+	Date           string `json:"date" dynamodbav:"date"`
+}
+
+// PlayerRating is a user's standing on one ladder
+type PlayerRating struct {
+	Ladder       string         `json:"ladder" dynamodbav:"ladder"` // "pvp" or "computer-{difficulty}"
+	UserId       string         `json:"userId" dynamodbav:"userId"`
+	Username     string         `json:"username" dynamodbav:"username"`
+	Rating       int            `json:"rating" dynamodbav:"rating"`
+	Battles      int            `json:"battles" dynamodbav:"battles"`
+	Wins         int            `json:"wins" dynamodbav:"wins"`
+	Losses       int            `json:"losses" dynamodbav:"losses"`
+	History      []RatingChange `json:"history,omitempty" dynamodbav:"history"` // Newest first
+	Version      int            `json:"-" dynamodbav:"version"`
+	UpdatedAt    string         `json:"updatedAt,omitempty" dynamodbav:"updatedAt"`
+	LastBattleId string         `json:"-" dynamodbav:"lastBattleId"` // Most recent battle applied, so repeats are skipped
+}
+
+// RatingStore persists ladder ratings
+type RatingStore interface {
+	// Get returns a player's rating, or a new DefaultRating entry if they haven't played
+	Get(ladder, userId string) (*PlayerRating, error)
+	// Update applies update to a player's rating atomically
+	Update(ladder, userId string, update func(rating *PlayerRating)) error
+	// UpdatePair applies update to two players' ratings on a ladder in one atomic write
+	UpdatePair(ladder, userId, opponentId string, update func(rating, opponent *PlayerRating)) error
+	// Leaderboard returns up to limit players by rating, highest first, starting after cursor
+	Leaderboard(ladder string, limit int, cursor string) ([]PlayerRating, string, error)
+}
+
+type LeaderboardResponse struct {
+	Ladder     string         `json:"ladder,omitempty"`
+	Players    []PlayerRating `json:"players,omitempty"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+	Error      string         `json:"error,omitempty"`
+}
+
+type RatingResponse struct {
+	Rating *PlayerRating `json:"rating,omitempty"`
+	Error  string        `json:"error,omitempty"`
+}
+
+var (
+	ratingStore     RatingStore
+	ratingStoreOnce sync.Once
+)
+
+// getRatingStore returns the configured rating store, using the same backend as battle storage
+func getRatingStore() RatingStore {
+	ratingStoreOnce.Do(func() {
+		storageConfig := config.LoadBattleStorageConfig()
+
+		if storageConfig.Backend == "dynamodb" {
+			store, err := newDynamoRatingStore(storageConfig.RatingTableName)
+			if err == nil {
+				ratingStore = store
+				return
+			}
+			log.Fatalf("Error creating DynamoDB rating store: %v", err)
+		}
+
+		ratingStore = newMemoryRatingStore()
+	})
+	return ratingStore
+}
+
+// validLadder reports whether name is a ladder that can be queried
+func validLadder(name string) bool {
+	if name == LadderPvP {
+		return true
+	}
+	_, ok := computerRatings[strings.TrimPrefix(name, "computer-")]
+	return ok && strings.HasPrefix(name, "computer-")
+}
+
+// expectedScore is the Elo probability of a player beating their opponent
+func expectedScore(rating, opponentRating int) float64 {
+	return 1 / (1 + math.Pow(10, float64(opponentRating-rating)/400))
+}
+
+// ratingDelta returns the rating change for a player after a battle
+func ratingDelta(rating, opponentRating int, won bool, kFactor int) int {
+	score := 0.0
+	if won {
+		score = 1
+	}
+	return int(math.Round(float64(kFactor) * (score - expectedScore(rating, opponentRating))))
+}
+
+// applyRatingChange moves a rating by delta and records the change in its history
+func applyRatingChange(rating *PlayerRating, username string, delta int, change RatingChange) {
+	change.Before = rating.Rating
+	change.After = rating.Rating + delta
+
+	rating.Username = username
+	rating.Rating = change.After
+	rating.LastBattleId = change.BattleId
+	rating.Battles++
+	if change.Result == "won" {
+		rating.Wins++
+	} else {
+		rating.Losses++
+	}
+	rating.History = append([]RatingChange{change}, rating.History...)
+	if len(rating.History) > MaxRatingHistory {
+		rating.History = rating.History[:MaxRatingHistory]
+	}
+	rating.UpdatedAt = change.Date
+}
+
+// ratedBattle reports whether a battle has already been applied to a rating, so
+// rating the same battle twice is a no-op
+func ratedBattle(rating *PlayerRating, battleId string) bool {
+	if battleId == "" {
+		return false
+	}
+	if rating.LastBattleId == battleId {
+		return true
+	}
+	for _, change := range rating.History {
+		if change.BattleId == battleId {
+			return true
+		}
+	}
+	return false
+}
+
+// resultFor returns "won" or "lost"
+func resultFor(won bool) string {
+	if won {
+		return "won"
+	}
+	return "lost"
+}
+
+// updateRatings applies a finished battle to the ladder. PvP battles are always
+// rated; battles against the computer only when enabled, and never with a chosen seed.
+func updateRatings(battle *BattleState) error {
+	ladderConfig := config.LoadLadderConfig()
+	store := getRatingStore()
+	playerWon := battle.winningSide() == "player"
+
+	if battle.isPvP() {
+		// Both changes are based on the ratings as they are when the write happens, and
+		// are saved together so neither player can be rated without the other
+		return store.UpdatePair(LadderPvP, battle.UserId, battle.OpponentUserId, func(player, opponent *PlayerRating) {
+			if ratedBattle(player, battle.BattleId) || ratedBattle(opponent, battle.BattleId) {
+				return
+			}
+			playerDelta := ratingDelta(player.Rating, opponent.Rating, playerWon, ladderConfig.KFactor)
+			opponentDelta := ratingDelta(opponent.Rating, player.Rating, !playerWon, ladderConfig.KFactor)
+			playerBefore, opponentBefore := player.Rating, opponent.Rating
+
+			applyRatingChange(player, battle.PlayerName, playerDelta, RatingChange{
+				BattleId:       battle.BattleId,
+				Opponent:       battle.OpponentName,
+				OpponentRating: opponentBefore,
+				Result:         resultFor(playerWon),
+				Date:           battle.UpdatedAt,
+			})
+			applyRatingChange(opponent, battle.OpponentName, opponentDelta, RatingChange{
+				BattleId:       battle.BattleId,
+				Opponent:       battle.PlayerName,
+				OpponentRating: playerBefore,
+				Result:         resultFor(!playerWon),
+				Date:           battle.UpdatedAt,
+			})
+		})
+	}
+
+	computerRating, ok := computerRatings[battle.Difficulty]
+	if !ladderConfig.RateComputerBattles || battle.CustomSeed || !ok {
+		return nil
+	}
+
+	ladder := "computer-" + battle.Difficulty
+	return store.Update(ladder, battle.UserId, func(rating *PlayerRating) {
+		if ratedBattle(rating, battle.BattleId) {
+			return
+		}
+		delta := ratingDelta(rating.Rating, computerRating, playerWon, ladderConfig.KFactor)
+		applyRatingChange(rating, battle.PlayerName, delta, RatingChange{
+			BattleId:       battle.BattleId,
+			Opponent:       "Computer",
+			OpponentRating: computerRating,
+			Result:         resultFor(playerWon),
+			Date:           battle.UpdatedAt,
+		})
+	})
+}
+
+// leaderboardCursor marks the last player on a leaderboard page
+type leaderboardCursor struct {
+	Rating int    `json:"rating"`
+	UserId string `json:"userId"`
+}
+
+func encodeLeaderboardCursor(rating PlayerRating) string {
+	data, _ := json.Marshal(leaderboardCursor{Rating: rating.Rating, UserId: rating.UserId})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeLeaderboardCursor(cursor string) (*leaderboardCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var decoded leaderboardCursor
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &decoded, nil
+}
+
+// memoryRatingStore keeps ratings in memory. Ratings are lost on restart.
+type memoryRatingStore struct {
+	ratings map[string]map[string]PlayerRating // Ladder, then user
+	mutex   *sync.RWMutex
+}
+
+func newMemoryRatingStore() *memoryRatingStore {
+	return &memoryRatingStore{
+		ratings: make(map[string]map[string]PlayerRating),
+		mutex:   &sync.RWMutex{},
+	}
+}
+
+func (s *memoryRatingStore) Get(ladder, userId string) (*PlayerRating, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	rating, ok := s.ratings[ladder][userId]
+	if !ok {
+		return &PlayerRating{Ladder: ladder, UserId: userId, Rating: DefaultRating}, nil
+	}
+	rating.History = append([]RatingChange(nil), rating.History...)
+	return &rating, nil
+}
+
+func (s *memoryRatingStore) Update(ladder, userId string, update func(rating *PlayerRating)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	rating, ok := s.ratings[ladder][userId]
+	if !ok {
+		rating = PlayerRating{Ladder: ladder, UserId: userId, Rating: DefaultRating}
+	}
+	update(&rating)
+	rating.Version++
+
+	if s.ratings[ladder] == nil {
+		s.ratings[ladder] = make(map[string]PlayerRating)
+	}
+	s.ratings[ladder][userId] = rating
+	return nil
+}
+
+func (s *memoryRatingStore) UpdatePair(ladder, userId, opponentId string, update func(rating, opponent *PlayerRating)) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ratings := make([]PlayerRating, 2)
+	for i, id := range []string{userId, opponentId} {
+		rating, ok := s.ratings[ladder][id]
+		if !ok {
+			rating = PlayerRating{Ladder: ladder, UserId: id, Rating: DefaultRating}
+		}
+		ratings[i] = rating
+	}
+	update(&ratings[0], &ratings[1])
+
+	if s.ratings[ladder] == nil {
+		s.ratings[ladder] = make(map[string]PlayerRating)
+	}
+	for _, rating := range ratings {
+		rating.Version++
+		s.ratings[ladder][rating.UserId] = rating
+	}
+	return nil
+}
+
+func (s *memoryRatingStore) Leaderboard(ladder string, limit int, cursor string) ([]PlayerRating, string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var after *leaderboardCursor
+	if cursor != "" {
+		var err error
+		if after, err = decodeLeaderboardCursor(cursor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	players := make([]PlayerRating, 0, len(s.ratings[ladder]))
+	for _, rating := range s.ratings[ladder] {
+		rating.History = nil
+		players = append(players, rating)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		if players[i].Rating != players[j].Rating {
+			return players[i].Rating > players[j].Rating
+		}
+		return players[i].UserId < players[j].UserId
+	})
+
+	var page []PlayerRating
+	for _, player := range players {
+		if after != nil && (player.Rating > after.Rating || (player.Rating == after.Rating && player.UserId <= after.UserId)) {
+			continue
+		}
+		if len(page) == limit {
+			return page, encodeLeaderboardCursor(page[len(page)-1]), nil
+		}
+		page = append(page, player)
+	}
+	return page, "", nil
+}
+
+// LeaderboardHandler lists a ladder's players by rating.
+// Query parameters: ladder (default "pvp"), limit (default 20, max 100) and cursor.
+func LeaderboardHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(LeaderboardResponse{Error: "Method not allowed"})
+		return
+	}
+
+	ladder := r.URL.Query().Get("ladder")
+	if ladder == "" {
+		ladder = LadderPvP
+	}
+	if !validLadder(ladder) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(LeaderboardResponse{Error: "Ladder must be: pvp, computer-easy, computer-normal, or computer-hard"})
+		return
+	}
+
+	limit := DefaultLeaderboardSize
+	if limitParam := r.URL.Query().Get("limit"); limitParam != "" {
+		parsed, err := strconv.Atoi(limitParam)
+		if err != nil || parsed < 1 || parsed > MaxLeaderboardSize {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(LeaderboardResponse{Error: fmt.Sprintf("Limit must be between 1 and %d", MaxLeaderboardSize)})
+			return
+		}
+		limit = parsed
+	}
+
+	players, nextCursor, err := getRatingStore().Leaderboard(ladder, limit, r.URL.Query().Get("cursor"))
+	if err != nil {
+		log.Printf("Error loading leaderboard: %v", err)
+		if errors.Is(err, ErrInvalidCursor) {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(LeaderboardResponse{Error: "Invalid cursor"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(LeaderboardResponse{Error: "Failed to load leaderboard"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(LeaderboardResponse{Ladder: ladder, Players: players, NextCursor: nextCursor})
+}
+
+// RatingHistoryHandler returns the user's rating and recent rating changes on a ladder
+func RatingHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(RatingResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(RatingResponse{Error: "Authentication required"})
+		return
+	}
+
+	ladder := r.URL.Query().Get("ladder")
+	if ladder == "" {
+		ladder = LadderPvP
+	}
+	if !validLadder(ladder) {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(RatingResponse{Error: "Ladder must be: pvp, computer-easy, computer-normal, or computer-hard"})
+		return
+	}
+
+	rating, err := getRatingStore().Get(ladder, user.Sub)
+	if err != nil {
+		log.Printf("Error loading rating: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(RatingResponse{Error: "Failed to load rating"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RatingResponse{Rating: rating})
+}
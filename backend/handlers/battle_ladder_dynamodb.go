@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	RatingIndexName         = "ladder-rating-index"
+	MaxRatingUpdateAttempts = 3
+)
+
+// dynamoRatingStore keeps ratings in a table keyed by ladder and userId, with a
+// ladder/rating index for leaderboards
+type dynamoRatingStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+func newDynamoRatingStore(tableName string) (*dynamoRatingStore, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &dynamoRatingStore{
+		client:    dynamodb.NewFromConfig(cfg),
+		tableName: tableName,
+	}, nil
+}
+
+func (s *dynamoRatingStore) Get(ladder, userId string) (*PlayerRating, error) {
+	result, err := s.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: &s.tableName,
+		Key: map[string]types.AttributeValue{
+			"ladder": &types.AttributeValueMemberS{Value: ladder},
+			"userId": &types.AttributeValueMemberS{Value: userId},
+		},
+		ConsistentRead: boolPtr(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rating: %w", err)
+	}
+	if result.Item == nil {
+		return &PlayerRating{Ladder: ladder, UserId: userId, Rating: DefaultRating}, nil
+	}
+
+	var rating PlayerRating
+	if err := attributevalue.UnmarshalMap(result.Item, &rating); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rating: %w", err)
+	}
+	return &rating, nil
+}
+
+// Update reads, modifies and conditionally writes the rating, retrying if another
+// battle updated it in between
+func (s *dynamoRatingStore) Update(ladder, userId string, update func(rating *PlayerRating)) error {
+	for attempt := 0; attempt < MaxRatingUpdateAttempts; attempt++ {
+		rating, err := s.Get(ladder, userId)
+		if err != nil {
+			return err
+		}
+
+		loadedVersion := rating.Version
+		update(rating)
+		rating.Version = loadedVersion + 1
+
+		item, err := attributevalue.MarshalMap(rating)
+		if err != nil {
+			return fmt.Errorf("failed to marshal rating: %w", err)
+		}
+
+		_, err = s.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+			TableName:           &s.tableName,
+			Item:                item,
+			ConditionExpression: stringPtr("attribute_not_exists(userId) OR version = :version"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":version": &types.AttributeValueMemberN{Value: strconv.Itoa(loadedVersion)},
+			},
+		})
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save rating: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("rating for %s on %s was updated concurrently too many times", userId, ladder)
+}
+
+// UpdatePair reads both ratings, modifies them and writes them in one transaction
+// conditioned on both versions, retrying if another battle updated either in between
+func (s *dynamoRatingStore) UpdatePair(ladder, userId, opponentId string, update func(rating, opponent *PlayerRating)) error {
+	for attempt := 0; attempt < MaxRatingUpdateAttempts; attempt++ {
+		rating, err := s.Get(ladder, userId)
+		if err != nil {
+			return err
+		}
+		opponent, err := s.Get(ladder, opponentId)
+		if err != nil {
+			return err
+		}
+
+		var items []types.TransactWriteItem
+		loadedVersions := []int{rating.Version, opponent.Version}
+		update(rating, opponent)
+		for i, updated := range []*PlayerRating{rating, opponent} {
+			updated.Version = loadedVersions[i] + 1
+			item, err := attributevalue.MarshalMap(updated)
+			if err != nil {
+				return fmt.Errorf("failed to marshal rating: %w", err)
+			}
+			items = append(items, types.TransactWriteItem{Put: &types.Put{
+				TableName:           &s.tableName,
+				Item:                item,
+				ConditionExpression: stringPtr("attribute_not_exists(userId) OR version = :version"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{
+					":version": &types.AttributeValueMemberN{Value: strconv.Itoa(loadedVersions[i])},
+				},
+			}})
+		}
+
+		_, err = s.client.TransactWriteItems(context.TODO(), &dynamodb.TransactWriteItemsInput{TransactItems: items})
+		var canceled *types.TransactionCanceledException
+		if errors.As(err, &canceled) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to save ratings: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("ratings for %s and %s on %s were updated concurrently too many times", userId, opponentId, ladder)
+}
+
+func (s *dynamoRatingStore) Leaderboard(ladder string, limit int, cursor string) ([]PlayerRating, string, error) {
+	limit32 := int32(limit)
+	input := &dynamodb.QueryInput{
+		TableName:              &s.tableName,
+		IndexName:              stringPtr(RatingIndexName),
+		KeyConditionExpression: stringPtr("ladder = :ladder"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":ladder": &types.AttributeValueMemberS{Value: ladder},
+		},
+		ProjectionExpression: stringPtr("ladder, userId, username, rating, battles, wins, losses, updatedAt"),
+		ScanIndexForward:     boolPtr(false), // Highest rating first
+		Limit:                &limit32,
+	}
+
+	if cursor != "" {
+		after, err := decodeLeaderboardCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		input.ExclusiveStartKey = map[string]types.AttributeValue{
+			"ladder": &types.AttributeValueMemberS{Value: ladder},
+			"userId": &types.AttributeValueMemberS{Value: after.UserId},
+			"rating": &types.AttributeValueMemberN{Value: strconv.Itoa(after.Rating)},
+		}
+	}
+
+	result, err := s.client.Query(context.TODO(), input)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to query leaderboard: %w", err)
+	}
+
+	var players []PlayerRating
+	if err := attributevalue.UnmarshalListOfMaps(result.Items, &players); err != nil {
+		return nil, "", fmt.Errorf("failed to unmarshal leaderboard: %w", err)
+	}
+
+	next := ""
+	if len(result.LastEvaluatedKey) > 0 && len(players) > 0 {
+		next = encodeLeaderboardCursor(players[len(players)-1])
+	}
+	return players, next, nil
+}
@@ -0,0 +1,209 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestRatingDelta(t *testing.T) {
+	tests := []struct {
+		name           string
+		rating         int
+		opponentRating int
+		won            bool
+		want           int
+	}{
+		{name: "even match win", rating: 1500, opponentRating: 1500, won: true, want: 16},
+		{name: "even match loss", rating: 1500, opponentRating: 1500, won: false, want: -16},
+		{name: "upset win", rating: 1200, opponentRating: 1600, won: true, want: 29},
+		{name: "expected win", rating: 1600, opponentRating: 1200, won: true, want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ratingDelta(tt.rating, tt.opponentRating, tt.won, 32); got != tt.want {
+				t.Errorf("ratingDelta() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidLadder(t *testing.T) {
+	for ladder, want := range map[string]bool{
+		"pvp":           true,
+		"computer-hard": true,
+		"computer-":     false,
+		"hard":          false,
+		"computer-evil": false,
+	} {
+		if got := validLadder(ladder); got != want {
+			t.Errorf("validLadder(%q) = %v, want %v", ladder, got, want)
+		}
+	}
+}
+
+func TestUpdateRatingsPvP(t *testing.T) {
+	battle := testPvPBattle()
+	battle.BattleId = "ladder-battle"
+	battle.UserId, battle.OpponentUserId = "ladder-winner", "ladder-loser"
+	battle.PlayerName, battle.OpponentName = "ash", "gary"
+	battle.BattleStatus = "won"
+
+	// Rating the same battle again changes nothing
+	for i := 0; i < 2; i++ {
+		if err := updateRatings(battle); err != nil {
+			t.Fatalf("updateRatings() error = %v", err)
+		}
+	}
+
+	winner, _ := getRatingStore().Get(LadderPvP, "ladder-winner")
+	loser, _ := getRatingStore().Get(LadderPvP, "ladder-loser")
+	if winner.Rating != DefaultRating+16 || loser.Rating != DefaultRating-16 {
+		t.Errorf("ratings = %d and %d, want %d and %d", winner.Rating, loser.Rating, DefaultRating+16, DefaultRating-16)
+	}
+	if winner.Wins != 1 || loser.Losses != 1 {
+		t.Errorf("winner wins = %d, loser losses = %d, want 1 and 1", winner.Wins, loser.Losses)
+	}
+	if len(winner.History) != 1 || winner.History[0].Opponent != "gary" || winner.History[0].Before != DefaultRating {
+		t.Errorf("winner history = %+v, want one change against gary from %d", winner.History, DefaultRating)
+	}
+}
+
+func TestUpdateRatingsComputer(t *testing.T) {
+	battle := testAIBattle()
+	battle.UserId = "ladder-computer-player"
+	battle.Difficulty = "hard"
+	battle.BattleStatus = "won"
+
+	// Computer battles are only rated when enabled
+	if err := updateRatings(battle); err != nil {
+		t.Fatalf("updateRatings() error = %v", err)
+	}
+	if rating, _ := getRatingStore().Get("computer-hard", battle.UserId); rating.Battles != 0 {
+		t.Errorf("rated a computer battle with the ladder disabled")
+	}
+
+	t.Setenv("LADDER_RATE_COMPUTER", "true")
+	battle.CustomSeed = true
+	updateRatings(battle)
+	if rating, _ := getRatingStore().Get("computer-hard", battle.UserId); rating.Battles != 0 {
+		t.Errorf("rated a battle with a chosen seed")
+	}
+
+	battle.CustomSeed = false
+	battle.BattleId = "ladder-computer-battle"
+	updateRatings(battle)
+	updateRatings(battle)
+	rating, _ := getRatingStore().Get("computer-hard", battle.UserId)
+	if rating.Battles != 1 || rating.Rating != DefaultRating+ratingDelta(DefaultRating, computerRatings["hard"], true, 32) {
+		t.Errorf("rating = %+v, want one win against the hard computer", rating)
+	}
+}
+
+func TestUpdateRatingsUsesCurrentRatings(t *testing.T) {
+	// Another battle moved the loser's rating after this one ended
+	getRatingStore().Update(LadderPvP, "ladder-current-loser", func(r *PlayerRating) { r.Rating = 1700 })
+
+	battle := testPvPBattle()
+	battle.BattleId = "ladder-current-battle"
+	battle.UserId, battle.OpponentUserId = "ladder-current-winner", "ladder-current-loser"
+	battle.BattleStatus = "won"
+	if err := updateRatings(battle); err != nil {
+		t.Fatalf("updateRatings() error = %v", err)
+	}
+
+	winner, _ := getRatingStore().Get(LadderPvP, "ladder-current-winner")
+	loser, _ := getRatingStore().Get(LadderPvP, "ladder-current-loser")
+	if want := DefaultRating + ratingDelta(DefaultRating, 1700, true, 32); winner.Rating != want {
+		t.Errorf("winner rating = %d, want %d", winner.Rating, want)
+	}
+	if want := 1700 + ratingDelta(1700, DefaultRating, false, 32); loser.Rating != want {
+		t.Errorf("loser rating = %d, want %d", loser.Rating, want)
+	}
+	if winner.History[0].OpponentRating != 1700 {
+		t.Errorf("winner history = %+v, want an opponent rating of 1700", winner.History)
+	}
+}
+
+func TestMemoryRatingStoreLeaderboard(t *testing.T) {
+	store := newMemoryRatingStore()
+	for i, rating := range []int{1400, 1600, 1500, 1600, 1300} {
+		rating := rating
+		store.Update(LadderPvP, fmt.Sprintf("user-%d", i), func(r *PlayerRating) {
+			r.Rating = rating
+			r.History = []RatingChange{{BattleId: "battle"}}
+		})
+	}
+	store.Update("computer-easy", "user-9", func(r *PlayerRating) { r.Rating = 2000 })
+
+	var ids []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 5 {
+			t.Fatal("pagination did not terminate")
+		}
+		page, next, err := store.Leaderboard(LadderPvP, 2, cursor)
+		if err != nil {
+			t.Fatalf("Leaderboard() error = %v", err)
+		}
+		for _, player := range page {
+			if len(player.History) != 0 {
+				t.Errorf("leaderboard entry for %s includes history", player.UserId)
+			}
+			ids = append(ids, player.UserId)
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	want := []string{"user-1", "user-3", "user-2", "user-0", "user-4"}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("leaderboard = %v, want %v", ids, want)
+	}
+}
+
+// failingRatingStore fails every leaderboard query, like an unreachable table
+type failingRatingStore struct {
+	*memoryRatingStore
+}
+
+func (s *failingRatingStore) Leaderboard(ladder string, limit int, cursor string) ([]PlayerRating, string, error) {
+	return nil, "", errors.New("table unavailable")
+}
+
+func TestLeaderboardHandlerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		cursor     string
+		failing    bool
+		wantStatus int
+	}{
+		{name: "valid request", wantStatus: http.StatusOK},
+		{name: "invalid cursor", cursor: "not base64!", wantStatus: http.StatusBadRequest},
+		{name: "store failure", failing: true, wantStatus: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := getRatingStore()
+			if tt.failing {
+				ratingStore = &failingRatingStore{newMemoryRatingStore()}
+				defer func() { ratingStore = store }()
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/ladder?cursor="+url.QueryEscape(tt.cursor), nil)
+			w := httptest.NewRecorder()
+			LeaderboardHandler(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("LeaderboardHandler() status = %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// critChances are the critical hit chances for each crit stage. High crit-rate
+// moves like Slash start at stage 1.
+var critChances = []float64{CriticalHitRate, 1.0 / 8, 1.0 / 2, 1}
+
+// criticalHitChance returns the chance of a move landing a critical hit
+func criticalHitChance(move *PokemonMove) float64 {
+	stage := min(max(move.CritRate, 0), len(critChances)-1)
+	return critChances[stage]
+}
+
+// rollHits returns how many times a move strikes, e.g. 2-5 for Fury Attack
+func rollHits(rng *rand.Rand, move *PokemonMove) int {
+	if move.MaxHits <= 1 {
+		return 1
+	}
+	minHits := max(move.MinHits, 1)
+	if move.MaxHits <= minHits {
+		return minHits
+	}
+	return minHits + rng.IntN(move.MaxHits-minHits+1)
+}
+
+// strike deals a damaging move's hits, stopping early if either Pokemon faints or
+// the target is immune. The defender's after-damage effects run after each hit.
+// Returns the total damage and whether any hit was critical, the hits that landed
+// and the effects' messages.
+func strike(rng *rand.Rand, field *FieldState, attacker, defender *BattlePokemon, move *PokemonMove) (damageResult, int, []string) {
+	var total damageResult
+	var messages []string
+	hits := rollHits(rng, move)
+	landed := 0
+	for landed < hits && defender.CurrentHP > 0 && attacker.CurrentHP > 0 {
+		result := calculateDamage(rng, field, attacker, defender, move)
+		defender.CurrentHP = max(0, defender.CurrentHP-result.Damage)
+		landed++
+
+		total.Damage += result.Damage
+		total.Effectiveness = result.Effectiveness
+		total.STAB = result.STAB
+		total.CriticalHit = total.CriticalHit || result.CriticalHit
+		messages = append(messages, afterDamageEffects(rng, defender, attacker, move, result.Damage, false)...)
+		if result.Effectiveness == 0 {
+			break
+		}
+	}
+	return total, landed, messages
+}
+
+// applyDrain heals the attacker by a share of the damage dealt for moves like Giga
+// Drain, or hurts it for recoil moves like Double-Edge, which have a negative drain
+func applyDrain(attacker, defender *BattlePokemon, move *PokemonMove, damage int, action *TurnAction) {
+	if move.Drain == 0 || damage == 0 || attacker.CurrentHP <= 0 {
+		return
+	}
+
+	before := attacker.CurrentHP
+	if move.Drain > 0 {
+		heal(attacker, damage*move.Drain/100)
+		action.Drained = attacker.CurrentHP - before
+		action.Message += fmt.Sprintf(" %s had its energy drained!", defender.Name)
+		return
+	}
+
+	hurt(attacker, damage*-move.Drain/100)
+	action.Recoil = before - attacker.CurrentHP
+	action.Message += fmt.Sprintf(" %s is damaged by recoil!", attacker.Name)
+}
+
+// applyHealing restores a share of the user's max HP for moves like Recover.
+// Returns false if the user's HP was already full.
+func applyHealing(attacker *BattlePokemon, move *PokemonMove, action *TurnAction) bool {
+	if attacker.CurrentHP >= attacker.MaxHP {
+		return false
+	}
+
+	before := attacker.CurrentHP
+	heal(attacker, attacker.MaxHP*move.Healing/100)
+	action.Healed = attacker.CurrentHP - before
+	action.Message += fmt.Sprintf(" %s regained health!", attacker.Name)
+	return true
+}
+
+// rollFlinch makes the target flinch for moves like Fake Out. A flinch only
+// matters if the target hasn't moved yet, and is cleared at the end of the turn.
+func rollFlinch(rng *rand.Rand, defender *BattlePokemon, move *PokemonMove) {
+	if move.FlinchChance > 0 && defender.CurrentHP > 0 && rng.IntN(100) < move.FlinchChance {
+		defender.Flinched = true
+	}
+}
+
+// flinchAction logs a Pokemon losing its turn to a flinch
+func flinchAction(pokemon *BattlePokemon, actor string, turnNumber int, timestamp string) *TurnAction {
+	pokemon.Flinched = false
+	return &TurnAction{
+		Turn:          turnNumber,
+		Actor:         actor,
+		Action:        "flinch",
+		MoveName:      pokemon.Name,
+		Effectiveness: 1,
+		Message:       fmt.Sprintf("%s flinched and couldn't move!", pokemon.Name),
+		Timestamp:     timestamp,
+	}
+}
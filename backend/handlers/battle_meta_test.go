@@ -0,0 +1,131 @@
+package handlers
+
+import "testing"
+
+func TestRollHits(t *testing.T) {
+	rng := newBattleRNG(1).Rand
+	furyAttack := &PokemonMove{MinHits: 2, MaxHits: 5}
+	seen := make(map[int]bool)
+	for i := 0; i < 200; i++ {
+		hits := rollHits(rng, furyAttack)
+		if hits < 2 || hits > 5 {
+			t.Fatalf("rollHits() = %d, want 2-5", hits)
+		}
+		seen[hits] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("rolled %v, want every count from 2 to 5", seen)
+	}
+
+	if hits := rollHits(rng, &PokemonMove{MinHits: 2, MaxHits: 2}); hits != 2 {
+		t.Errorf("double hit rolled %d, want 2", hits)
+	}
+	if hits := rollHits(rng, &PokemonMove{}); hits != 1 {
+		t.Errorf("single hit rolled %d, want 1", hits)
+	}
+}
+
+func TestCriticalHitChance(t *testing.T) {
+	tests := []struct {
+		critRate int
+		want     float64
+	}{
+		{0, CriticalHitRate},
+		{1, 1.0 / 8},
+		{2, 1.0 / 2},
+		{3, 1},
+		{6, 1},
+	}
+	for _, tt := range tests {
+		if got := criticalHitChance(&PokemonMove{CritRate: tt.critRate}); got != tt.want {
+			t.Errorf("criticalHitChance(%d) = %v, want %v", tt.critRate, got, tt.want)
+		}
+	}
+}
+
+func TestMoveMetaEffects(t *testing.T) {
+	tests := []struct {
+		name  string
+		move  PokemonMove
+		check func(t *testing.T, action *TurnAction, attacker, defender *BattlePokemon)
+	}{
+		{
+			name: "drain",
+			move: PokemonMove{Name: "giga-drain", Power: 75, Type: "grass", CurrentPP: 10, DamageClass: "special", Drain: 50},
+			check: func(t *testing.T, action *TurnAction, attacker, defender *BattlePokemon) {
+				if action.Drained != action.Damage/2 || attacker.CurrentHP != 50+action.Drained {
+					t.Errorf("drained %d of %d damage, HP = %d", action.Drained, action.Damage, attacker.CurrentHP)
+				}
+			},
+		},
+		{
+			name: "recoil",
+			move: PokemonMove{Name: "double-edge", Power: 120, Type: "normal", CurrentPP: 15, DamageClass: "physical", Drain: -33},
+			check: func(t *testing.T, action *TurnAction, attacker, defender *BattlePokemon) {
+				if action.Recoil != action.Damage*33/100 || attacker.CurrentHP != 50-action.Recoil {
+					t.Errorf("recoil %d of %d damage, HP = %d", action.Recoil, action.Damage, attacker.CurrentHP)
+				}
+			},
+		},
+		{
+			name: "healing",
+			move: PokemonMove{Name: "recover", Type: "normal", CurrentPP: 5, DamageClass: "status", Healing: 50},
+			check: func(t *testing.T, action *TurnAction, attacker, defender *BattlePokemon) {
+				if action.Healed != 75 || attacker.CurrentHP != 125 {
+					t.Errorf("healed %d, HP = %d, want 75 and 125", action.Healed, attacker.CurrentHP)
+				}
+			},
+		},
+		{
+			name: "multi-hit",
+			move: PokemonMove{Name: "double-kick", Power: 30, Type: "fighting", CurrentPP: 30, DamageClass: "physical", MinHits: 2, MaxHits: 2},
+			check: func(t *testing.T, action *TurnAction, attacker, defender *BattlePokemon) {
+				if action.Hits != 2 || defender.CurrentHP != 150-action.Damage {
+					t.Errorf("hits = %d, damage = %d, defender HP = %d", action.Hits, action.Damage, defender.CurrentHP)
+				}
+			},
+		},
+		{
+			name: "flinch",
+			move: PokemonMove{Name: "fake-out", Power: 40, Type: "normal", CurrentPP: 10, DamageClass: "physical", FlinchChance: 100},
+			check: func(t *testing.T, action *TurnAction, attacker, defender *BattlePokemon) {
+				if !defender.Flinched {
+					t.Error("defender didn't flinch")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			battle := testAIBattle()
+			battle.RNG = newBattleRNG(1)
+			attacker := battle.activePokemon("player")
+			defender := battle.activePokemon("computer")
+			attacker.CurrentHP = 50
+
+			action := executeMove(battle.random().Rand, nil, attacker, defender, &tt.move, "player", 1, "")
+			tt.check(t, action, attacker, defender)
+		})
+	}
+}
+
+func TestFlinchSkipsSlowerPokemon(t *testing.T) {
+	battle := testAIBattle()
+	battle.CurrentTurn = "player"
+	battle.BattleStatus = "active"
+	battle.OpponentStrategy = "greedy"
+	battle.RNG = newBattleRNG(1)
+	battle.PlayerTeam[0].Moves[0] = PokemonMove{Name: "fake-out", Power: 40, Type: "normal", CurrentPP: 10, DamageClass: "physical", FlinchChance: 100, Priority: 3}
+
+	turnResult, err := processBattleTurn(battle, BattleChoice{Action: "attack", MoveName: "fake-out"})
+	if err != nil {
+		t.Fatalf("processBattleTurn() error = %v", err)
+	}
+	if turnResult.ComputerAction == nil || turnResult.ComputerAction.Action != "flinch" {
+		t.Errorf("computer action = %+v, want a flinch", turnResult.ComputerAction)
+	}
+	if battle.activePokemon("computer").Flinched {
+		t.Error("flinch wasn't cleared at the end of the turn")
+	}
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	MaxMoves                 = 4
+	MaxConcurrentMoveFetches = 8
+	MaxDefaultMoveCandidates = 12 // Most recently learned level-up moves fetched when picking a default moveset
+)
+
+// ErrMoveNotLearnable is returned when a requested move isn't in the Pokemon's learnset
+var ErrMoveNotLearnable = errors.New("move not learnable")
+
+// Move details never change, so they are cached for the lifetime of the server
+var (
+	moveCache      = make(map[string]PokemonMove)
+	moveCacheMutex = &sync.RWMutex{}
+)
+
+// learnsetEntry describes how a Pokemon learns a move
+type learnsetEntry struct {
+	LevelUp      bool
+	LevelLearned int // Lowest level the move is learned at by level-up
+}
+
+func getCachedMove(moveName string) (PokemonMove, bool) {
+	moveCacheMutex.RLock()
+	defer moveCacheMutex.RUnlock()
+	move, ok := moveCache[moveName]
+	return move, ok
+}
+
+func cacheMove(move PokemonMove) {
+	moveCacheMutex.Lock()
+	defer moveCacheMutex.Unlock()
+	moveCache[move.Name] = move
+}
+
+// normalizeMoveName converts user input like "Thunder Punch" to PokeAPI's "thunder-punch"
+func normalizeMoveName(moveName string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(moveName)), " ", "-")
+}
+
+// parseLearnset extracts every move a Pokemon can learn from PokeAPI's moves array
+func parseLearnset(movesData []interface{}) map[string]learnsetEntry {
+	learnset := make(map[string]learnsetEntry)
+	for _, moveInfo := range movesData {
+		moveMap, ok := moveInfo.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		moveData, ok := moveMap["move"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		moveName, ok := moveData["name"].(string)
+		if !ok {
+			continue
+		}
+
+		entry := learnsetEntry{}
+		if details, ok := moveMap["version_group_details"].([]interface{}); ok {
+			for _, detail := range details {
+				detailMap, ok := detail.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				method, _ := detailMap["move_learn_method"].(map[string]interface{})
+				if methodName, _ := method["name"].(string); methodName != "level-up" {
+					continue
+				}
+				level, _ := detailMap["level_learned_at"].(float64)
+				if !entry.LevelUp || int(level) < entry.LevelLearned {
+					entry.LevelLearned = int(level)
+				}
+				entry.LevelUp = true
+			}
+		}
+		learnset[moveName] = entry
+	}
+	return learnset
+}
+
+// fetchMoves fetches move details concurrently, preserving the order of names
+func fetchMoves(moveNames []string) []PokemonMove {
+	moves := make([]PokemonMove, len(moveNames))
+	semaphore := make(chan struct{}, MaxConcurrentMoveFetches)
+
+	var wg sync.WaitGroup
+	for i, moveName := range moveNames {
+		wg.Add(1)
+		go func(i int, moveName string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			moves[i] = fetchMoveDetails(moveName)
+		}(i, moveName)
+	}
+	wg.Wait()
+
+	return moves
+}
+
+// selectMoveset returns the chosen moves after checking them against the learnset,
+// or the default moveset for the Pokemon's level when none are chosen
+func selectMoveset(pokemonName string, learnset map[string]learnsetEntry, chosen []string, level int, types []string) ([]PokemonMove, error) {
+	if len(chosen) > MaxMoves {
+		return nil, fmt.Errorf("%s can know at most %d moves", pokemonName, MaxMoves)
+	}
+
+	if len(chosen) > 0 {
+		seen := make(map[string]bool)
+		names := make([]string, 0, len(chosen))
+		for _, moveName := range chosen {
+			moveName = normalizeMoveName(moveName)
+			if _, ok := learnset[moveName]; !ok {
+				return nil, fmt.Errorf("%w: %s can't learn %s", ErrMoveNotLearnable, pokemonName, moveName)
+			}
+			if seen[moveName] {
+				return nil, fmt.Errorf("%s is listed more than once", moveName)
+			}
+			seen[moveName] = true
+			names = append(names, moveName)
+		}
+		return fetchMoves(names), nil
+	}
+
+	return pickDefaultMoves(fetchMoves(defaultMoveCandidates(learnset, level)), types), nil
+}
+
+// defaultMoveCandidates returns the level-up moves most recently learned by the battle
+// level, falling back to every level-up move. Only these are fetched from PokeAPI, so
+// a default moveset costs at most MaxDefaultMoveCandidates requests.
+func defaultMoveCandidates(learnset map[string]learnsetEntry, level int) []string {
+	var candidates []string
+	for moveName, entry := range learnset {
+		if entry.LevelUp && entry.LevelLearned <= level {
+			candidates = append(candidates, moveName)
+		}
+	}
+	if len(candidates) == 0 {
+		for moveName, entry := range learnset {
+			if entry.LevelUp {
+				candidates = append(candidates, moveName)
+			}
+		}
+	}
+
+	// Moves learned later are usually stronger
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := learnset[candidates[i]], learnset[candidates[j]]
+		if a.LevelLearned != b.LevelLearned {
+			return a.LevelLearned > b.LevelLearned
+		}
+		return candidates[i] < candidates[j]
+	})
+	if len(candidates) > MaxDefaultMoveCandidates {
+		candidates = candidates[:MaxDefaultMoveCandidates]
+	}
+	return candidates
+}
+
+// moveScore estimates a move's expected power, including accuracy and STAB
+func moveScore(move PokemonMove, types []string) float64 {
+	if move.DamageClass == "status" {
+		return 0
+	}
+
+	score := float64(move.Power)
+	if move.Accuracy > 0 {
+		score *= float64(move.Accuracy) / 100
+	}
+	for _, pokemonType := range types {
+		if strings.EqualFold(pokemonType, move.Type) {
+			score *= STABMultiplier
+			break
+		}
+	}
+	return score
+}
+
+// pickDefaultMoves chooses the strongest damaging moves while preferring new move types
+// for coverage, then fills any remaining slots with status moves that have an effect
+func pickDefaultMoves(candidates []PokemonMove, types []string) []PokemonMove {
+	var damaging, status []PokemonMove
+	for _, move := range candidates {
+		if move.DamageClass == "status" {
+			if move.Ailment != "" || len(move.StatChanges) > 0 {
+				status = append(status, move)
+			}
+		} else if move.Power > 0 {
+			damaging = append(damaging, move)
+		}
+	}
+
+	sort.SliceStable(damaging, func(i, j int) bool {
+		return moveScore(damaging[i], types) > moveScore(damaging[j], types)
+	})
+
+	var picked []PokemonMove
+	used := make(map[int]bool)
+	coveredTypes := make(map[string]bool)
+
+	// First pass takes the strongest move of each type, second pass fills remaining slots
+	for pass := 0; pass < 2; pass++ {
+		for i, move := range damaging {
+			if len(picked) >= MaxMoves {
+				break
+			}
+			if used[i] || (pass == 0 && coveredTypes[move.Type]) {
+				continue
+			}
+			picked = append(picked, move)
+			used[i] = true
+			coveredTypes[move.Type] = true
+		}
+	}
+
+	for _, move := range status {
+		if len(picked) >= MaxMoves {
+			break
+		}
+		picked = append(picked, move)
+	}
+
+	return picked
+}
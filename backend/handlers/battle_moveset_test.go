@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseLearnset(t *testing.T) {
+	movesData := []interface{}{
+		map[string]interface{}{
+			"move": map[string]interface{}{"name": "thunder-shock"},
+			"version_group_details": []interface{}{
+				map[string]interface{}{
+					"level_learned_at":  float64(5),
+					"move_learn_method": map[string]interface{}{"name": "level-up"},
+				},
+				map[string]interface{}{
+					"level_learned_at":  float64(1),
+					"move_learn_method": map[string]interface{}{"name": "level-up"},
+				},
+			},
+		},
+		map[string]interface{}{
+			"move": map[string]interface{}{"name": "surf"},
+			"version_group_details": []interface{}{
+				map[string]interface{}{
+					"level_learned_at":  float64(0),
+					"move_learn_method": map[string]interface{}{"name": "machine"},
+				},
+			},
+		},
+	}
+
+	learnset := parseLearnset(movesData)
+
+	if entry := learnset["thunder-shock"]; !entry.LevelUp || entry.LevelLearned != 1 {
+		t.Errorf("thunder-shock = %+v, want level-up at 1", entry)
+	}
+	if entry, ok := learnset["surf"]; !ok || entry.LevelUp {
+		t.Errorf("surf = %+v (present %v), want a non level-up entry", entry, ok)
+	}
+}
+
+func TestSelectMovesetRejectsUnlearnableMoves(t *testing.T) {
+	learnset := map[string]learnsetEntry{"thunderbolt": {LevelUp: true, LevelLearned: 26}}
+
+	_, err := selectMoveset("pikachu", learnset, []string{"Hydro Pump"}, 50, []string{"electric"})
+	if !errors.Is(err, ErrMoveNotLearnable) {
+		t.Errorf("selectMoveset() error = %v, want ErrMoveNotLearnable", err)
+	}
+
+	_, err = selectMoveset("pikachu", learnset, []string{"a", "b", "c", "d", "e"}, 50, []string{"electric"})
+	if err == nil {
+		t.Error("selectMoveset() accepted more than four moves")
+	}
+}
+
+func TestDefaultMoveCandidates(t *testing.T) {
+	learnset := map[string]learnsetEntry{"surf": {}, "too-late": {LevelUp: true, LevelLearned: 60}}
+	for level := 1; level <= 40; level++ {
+		learnset[fmt.Sprintf("move-%02d", level)] = learnsetEntry{LevelUp: true, LevelLearned: level}
+	}
+
+	candidates := defaultMoveCandidates(learnset, 50)
+	if len(candidates) != MaxDefaultMoveCandidates {
+		t.Fatalf("got %d candidates, want %d", len(candidates), MaxDefaultMoveCandidates)
+	}
+	if candidates[0] != "move-40" || candidates[len(candidates)-1] != "move-29" {
+		t.Errorf("candidates = %v, want the most recently learned moves from move-40 down", candidates)
+	}
+
+	// Below every move's level, fall back to the level-up moves
+	if candidates := defaultMoveCandidates(map[string]learnsetEntry{"surf": {}, "too-late": {LevelUp: true, LevelLearned: 60}}, 5); len(candidates) != 1 || candidates[0] != "too-late" {
+		t.Errorf("candidates = %v, want [too-late]", candidates)
+	}
+}
+
+func TestPickDefaultMoves(t *testing.T) {
+	candidates := []PokemonMove{
+		{Name: "thunder-shock", Power: 40, Type: "electric", Accuracy: 100, DamageClass: "special"},
+		{Name: "thunderbolt", Power: 90, Type: "electric", Accuracy: 100, DamageClass: "special"},
+		{Name: "thunder", Power: 110, Type: "electric", Accuracy: 70, DamageClass: "special"},
+		{Name: "iron-tail", Power: 100, Type: "steel", Accuracy: 75, DamageClass: "physical"},
+		{Name: "quick-attack", Power: 40, Type: "normal", Accuracy: 100, DamageClass: "physical"},
+		{Name: "thunder-wave", Type: "electric", DamageClass: "status", Ailment: StatusParalysis},
+		{Name: "tail-whip", Type: "normal", DamageClass: "status", StatChanges: []StatChange{{Stat: "defense", Change: -1}}},
+	}
+
+	picked := pickDefaultMoves(candidates, []string{"electric"})
+
+	want := []string{"thunderbolt", "iron-tail", "quick-attack", "thunder"}
+	if len(picked) != len(want) {
+		t.Fatalf("picked %d moves, want %d", len(picked), len(want))
+	}
+	for i, name := range want {
+		if picked[i].Name != name {
+			t.Errorf("picked[%d] = %s, want %s", i, picked[i].Name, name)
+		}
+	}
+}
+
+func TestPickDefaultMovesFillsWithStatusMoves(t *testing.T) {
+	candidates := []PokemonMove{
+		{Name: "tackle", Power: 40, Type: "normal", Accuracy: 100, DamageClass: "physical"},
+		{Name: "growl", Type: "normal", DamageClass: "status", StatChanges: []StatChange{{Stat: "attack", Change: -1}}},
+		{Name: "splash", Type: "normal", DamageClass: "status"},
+	}
+
+	picked := pickDefaultMoves(candidates, []string{"normal"})
+	if len(picked) != 2 || picked[0].Name != "tackle" || picked[1].Name != "growl" {
+		t.Errorf("pickDefaultMoves() = %+v, want tackle and growl", picked)
+	}
+}
@@ -0,0 +1,61 @@
+package handlers
+
+// TurnOrder records which side acted first in a turn and why
+type TurnOrder struct {
+	Order    []string       `json:"order"`    // Sides in the order they acted
+	Reason   string         `json:"reason"`   // "priority", "speed" or "speed-tie"
+	Priority map[string]int `json:"priority"` // Priority of each side's move, 0 for switches
+	Speed    map[string]int `json:"speed"`    // Speed after stat stages and paralysis
+}
+
+// choicePriority returns the priority of a side's move. Switches always happen
+// before any move, so their priority doesn't matter.
+func choicePriority(battle *BattleState, actor string, choice BattleChoice) int {
+	if choice.Action != "attack" {
+		return 0
+	}
+	if move := moveFor(battle.activePokemon(actor), choice.MoveName); move != nil {
+		return move.Priority
+	}
+	return 0
+}
+
+// decideTurnOrder orders the sides by move priority, then by effective speed.
+// Speed ties are broken with the battle's RNG so they replay the same way.
+func decideTurnOrder(battle *BattleState, choices map[string]BattleChoice) *TurnOrder {
+	turnOrder := &TurnOrder{Priority: make(map[string]int), Speed: make(map[string]int)}
+	speeds := make(map[string]float64)
+	for _, actor := range []string{"player", "computer"} {
+		turnOrder.Priority[actor] = choicePriority(battle, actor, choices[actor])
+		speeds[actor] = effectiveSpeed(battle.activePokemon(actor))
+		turnOrder.Speed[actor] = int(speeds[actor])
+	}
+
+	var playerFirst bool
+	switch {
+	case turnOrder.Priority["player"] != turnOrder.Priority["computer"]:
+		turnOrder.Reason = "priority"
+		playerFirst = turnOrder.Priority["player"] > turnOrder.Priority["computer"]
+	case speeds["player"] != speeds["computer"]:
+		turnOrder.Reason = "speed"
+		playerFirst = speeds["player"] > speeds["computer"]
+	default:
+		turnOrder.Reason = "speed-tie"
+		playerFirst = battle.random().IntN(2) == 0
+	}
+
+	turnOrder.Order = []string{"computer", "player"}
+	if playerFirst {
+		turnOrder.Order = []string{"player", "computer"}
+	}
+	return turnOrder
+}
+
+// movesFirst reports whether a Pokemon using move acts before a foe using foeMove.
+// The AI uses it to look ahead, counting speed ties in its own favour.
+func movesFirst(self, foe *BattlePokemon, move, foeMove *PokemonMove) bool {
+	if move.Priority != foeMove.Priority {
+		return move.Priority > foeMove.Priority
+	}
+	return effectiveSpeed(self) >= effectiveSpeed(foe)
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import "testing"
+
+func TestDecideTurnOrder(t *testing.T) {
+	quickAttack := PokemonMove{Name: "quick-attack", Power: 40, Type: "normal", CurrentPP: 30, Accuracy: 100, DamageClass: "physical", Priority: 1}
+	attack := func(moveName string) BattleChoice { return BattleChoice{Action: "attack", MoveName: moveName} }
+
+	tests := []struct {
+		name       string
+		setup      func(battle *BattleState)
+		choices    map[string]BattleChoice
+		wantFirst  string
+		wantReason string
+	}{
+		{
+			name:       "faster side",
+			setup:      func(battle *BattleState) { battle.ComputerTeam[0].Stats.Speed = 120 },
+			choices:    map[string]BattleChoice{"player": attack("ember"), "computer": attack("tackle")},
+			wantFirst:  "computer",
+			wantReason: "speed",
+		},
+		{
+			name: "priority beats speed",
+			setup: func(battle *BattleState) {
+				battle.ComputerTeam[0].Stats.Speed = 120
+				battle.PlayerTeam[0].Moves = append(battle.PlayerTeam[0].Moves, quickAttack)
+			},
+			choices:    map[string]BattleChoice{"player": attack("quick-attack"), "computer": attack("tackle")},
+			wantFirst:  "player",
+			wantReason: "priority",
+		},
+		{
+			name: "paralysis",
+			setup: func(battle *BattleState) {
+				battle.ComputerTeam[0].Stats.Speed = 120
+				battle.ComputerTeam[0].Status = StatusParalysis
+			},
+			choices:    map[string]BattleChoice{"player": attack("ember"), "computer": attack("tackle")},
+			wantFirst:  "player",
+			wantReason: "speed",
+		},
+		{
+			name:       "stat stages",
+			setup:      func(battle *BattleState) { battle.PlayerTeam[0].StatStages.Speed = -1 },
+			choices:    map[string]BattleChoice{"player": attack("ember"), "computer": attack("tackle")},
+			wantFirst:  "computer",
+			wantReason: "speed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			battle := testAIBattle()
+			tt.setup(battle)
+
+			turnOrder := decideTurnOrder(battle, tt.choices)
+			if turnOrder.Order[0] != tt.wantFirst || turnOrder.Reason != tt.wantReason {
+				t.Errorf("decideTurnOrder() = %v (%s), want %s first (%s)", turnOrder.Order, turnOrder.Reason, tt.wantFirst, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestDecideTurnOrderSpeedTies(t *testing.T) {
+	choices := map[string]BattleChoice{"player": {Action: "attack", MoveName: "ember"}, "computer": {Action: "attack", MoveName: "tackle"}}
+
+	first := make(map[string]int)
+	for seed := int64(1); seed <= 20; seed++ {
+		battle := testAIBattle()
+		battle.Seed = seed
+		turnOrder := decideTurnOrder(battle, choices)
+		if turnOrder.Reason != "speed-tie" {
+			t.Fatalf("reason = %s, want speed-tie", turnOrder.Reason)
+		}
+		first[turnOrder.Order[0]]++
+
+		replayed := testAIBattle()
+		replayed.Seed = seed
+		if again := decideTurnOrder(replayed, choices); again.Order[0] != turnOrder.Order[0] {
+			t.Errorf("seed %d: tie went to %s, then %s", seed, turnOrder.Order[0], again.Order[0])
+		}
+	}
+	if first["player"] == 0 || first["computer"] == 0 {
+		t.Errorf("ties went %v, want both sides to win some", first)
+	}
+}
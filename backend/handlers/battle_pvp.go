@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"backend/middleware"
+)
+
+// ChallengeRequest is the team a user brings to a PvP battle, both when
+// creating a challenge and when accepting one
+type ChallengeRequest struct {
+	TeamIds   []int           `json:"teamIds"`             // 1-6 Pokemon IDs
+	Level     int             `json:"level,omitempty"`     // Team level, defaults to 50
+	Spreads   []PokemonSpread `json:"spreads,omitempty"`   // Optional per-member spreads, in team order
+	Movesets  [][]string      `json:"movesets,omitempty"`  // Optional per-member move names (up to 4), in team order
+	Abilities []string        `json:"abilities,omitempty"` // Optional per-member abilities, each one the Pokemon can legally have
+	Items     []string        `json:"items,omitempty"`     // Optional per-member held items, in team order
+}
+
+type ChallengeResponse struct {
+	Battle *BattleState `json:"battle,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// isPvP reports whether both sides are controlled by users
+func (b *BattleState) isPvP() bool {
+	return b.Mode == BattleModePvP
+}
+
+// sideOf returns the side a user controls, or "" if they aren't in the battle.
+// In PvP the opponent controls the "computer" side.
+func (b *BattleState) sideOf(userId string) string {
+	switch {
+	case userId == b.UserId:
+		return "player"
+	case b.OpponentUserId != "" && userId == b.OpponentUserId:
+		return "computer"
+	default:
+		return ""
+	}
+}
+
+// controlledByUser reports whether a side's choices come from a user rather than a strategy
+func (b *BattleState) controlledByUser(actor string) bool {
+	return actor == "player" || b.isPvP()
+}
+
+// mustSwitch reports whether a side has to replace a fainted Pokemon
+func (b *BattleState) mustSwitch(actor string) bool {
+	for _, side := range b.ForcedSwitches {
+		if side == actor {
+			return true
+		}
+	}
+	return false
+}
+
+// trainerName is how a side is referred to in the battle log
+func (b *BattleState) trainerName(actor string) string {
+	switch {
+	case actor == "player" && b.isPvP():
+		return b.PlayerName
+	case actor == "player":
+		return "You"
+	case b.isPvP():
+		return b.OpponentName
+	default:
+		return "The computer"
+	}
+}
+
+// choosingSides returns the sides that have to submit a choice before the turn can resolve
+func (b *BattleState) choosingSides() []string {
+	if b.CurrentTurn == "switch" {
+		return b.ForcedSwitches
+	}
+	return []string{"player", "computer"}
+}
+
+// submitChoice stores one side's choice in a PvP battle and resolves the turn once
+// every side that needs to act has chosen, or a forfeit ends the battle. Returns a
+// nil result while waiting.
+func submitChoice(battle *BattleState, actor string, choice BattleChoice) (*TurnResult, error) {
+	// Either side can give up at any time, even after choosing this turn
+	if choice.Action == "run" {
+		return nil, fmt.Errorf("you can't run from a battle against another trainer")
+	}
+	if isForfeit(choice) {
+		return forfeitBattle(battle, actor, choice.Action), nil
+	}
+
+	if _, ok := battle.PendingChoices[actor]; ok {
+		return nil, fmt.Errorf("you have already chosen an action this turn")
+	}
+	if err := validateChoice(battle, actor, choice); err != nil {
+		return nil, err
+	}
+
+	if battle.PendingChoices == nil {
+		battle.PendingChoices = make(map[string]BattleChoice)
+	}
+	battle.PendingChoices[actor] = choice
+
+	for _, side := range battle.choosingSides() {
+		if _, ok := battle.PendingChoices[side]; !ok {
+			return nil, nil
+		}
+	}
+
+	choices := battle.PendingChoices
+	battle.PendingChoices = nil
+	return resolveTurn(battle, choices), nil
+}
+
+// viewFor returns a copy of the battle that only includes the viewer's own pending
+// choice and legal actions. Only the owner sees the spectator token; "" gives the spectator view.
+// The seed is revealed once the battle is over.
+func (b *BattleState) viewFor(actor string) *BattleState {
+	view := *b
+	view.PendingChoices = nil
+	if choice, ok := b.PendingChoices[actor]; ok {
+		view.PendingChoices = map[string]BattleChoice{actor: choice}
+	}
+	if actor != "player" {
+		view.SpectatorToken = ""
+	}
+	view.StreamTokens = nil
+	view.LegalActions = b.legalActions(actor)
+
+	// Replay data is served by the replay endpoint, and knowing the seed would let players predict rolls
+	view.InitialPlayerTeam = nil
+	view.InitialComputerTeam = nil
+	view.Choices = nil
+	view.RNG = nil
+	if view.BattleStatus == "pending" || view.BattleStatus == "active" {
+		view.Seed = 0
+	}
+	return &view
+}
+
+// fetchChallengeTeam validates and fetches the team for a challenge request.
+// The returned status code tells the handler how to report an error.
+func fetchChallengeTeam(req ChallengeRequest) ([]BattlePokemon, int, error) {
+	if len(req.TeamIds) == 0 {
+		return nil, http.StatusBadRequest, fmt.Errorf("at least one Pokemon ID is required")
+	}
+	if err := validateTeamRequest(req.TeamIds, req.Movesets); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+	if err := validateLoadout(len(req.TeamIds), req.Abilities, req.Items); err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	spreads, err := buildSpreads(len(req.TeamIds), req.Level, req.Spreads)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	team, err := fetchBattleTeam(req.TeamIds, spreads, req.Movesets, req.Abilities, req.Items)
+	if err != nil {
+		log.Printf("Error fetching challenge team: %v", err)
+		if isLoadoutError(err) {
+			return nil, http.StatusBadRequest, err
+		}
+		return nil, http.StatusBadRequest, fmt.Errorf("Failed to fetch Pokemon data")
+	}
+	return team, http.StatusOK, nil
+}
+
+// CreateChallengeHandler opens a PvP battle that another user can accept
+func CreateChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "Authentication required"})
+		return
+	}
+
+	var req ChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "Invalid request body"})
+		return
+	}
+
+	team, status, err := fetchChallengeTeam(req)
+	if err != nil {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: err.Error()})
+		return
+	}
+
+	now := time.Now()
+	battle := &BattleState{
+		UserId:       user.Sub,
+		Mode:         BattleModePvP,
+		PlayerName:   user.Username,
+		PlayerTeam:   team,
+		ComputerTeam: []BattlePokemon{},
+		CurrentTurn:  "player",
+		BattleStatus: "pending",
+		Seed:         newBattleSeed(),
+		CreatedAt:    now.Format(time.RFC3339),
+		UpdatedAt:    now.Format(time.RFC3339),
+		TurnHistory:  []TurnAction{},
+	}
+
+	if err := createBattle(battle, user.Sub, now); err != nil {
+		log.Printf("Error saving battle state: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "Failed to save battle state"})
+		return
+	}
+
+	log.Printf("User %s created challenge %s with: %s", user.Username, battle.BattleId, teamNames(team))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ChallengeResponse{Battle: battle.viewFor("player")})
+}
+
+// AcceptChallengeHandler joins an open PvP challenge as the opponent
+func AcceptChallengeHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "Authentication required"})
+		return
+	}
+
+	// Extract battle ID from URL path
+	// Expected format: /battle/{battleId}/accept
+	battleId := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/battle/"), "/accept")
+	if battleId == "" || strings.Contains(battleId, "/") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "Battle ID required"})
+		return
+	}
+
+	var req ChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "Invalid request body"})
+		return
+	}
+
+	// The challenged user isn't part of the battle yet, so load it without the owner check
+	battle, err := getBattleStore().Load(battleId)
+	if err != nil || !battle.isPvP() {
+		log.Printf("Error loading challenge: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "Challenge not found"})
+		return
+	}
+
+	if battle.BattleStatus != "pending" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "Challenge has already been accepted"})
+		return
+	}
+
+	if battle.UserId == user.Sub {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "You can't accept your own challenge"})
+		return
+	}
+
+	team, status, err := fetchChallengeTeam(req)
+	if err != nil {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: err.Error()})
+		return
+	}
+
+	battle.OpponentUserId = user.Sub
+	battle.OpponentName = user.Username
+	battle.ComputerTeam = team
+	battle.InitialPlayerTeam = cloneTeam(battle.PlayerTeam)
+	battle.InitialComputerTeam = cloneTeam(team)
+	battle.BattleStatus = "active"
+	now := time.Now()
+	applyLeadEffects(battle, now.Format(time.RFC3339))
+	battle.startTurnClock(now)
+	battle.UpdatedAt = now.Format(time.RFC3339)
+
+	if err := saveBattleState(battle); err != nil {
+		log.Printf("Error saving battle state: %v", err)
+		if errors.Is(err, ErrBattleConflict) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(ChallengeResponse{Error: "Challenge has already been accepted"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(ChallengeResponse{Error: "Failed to save battle state"})
+		return
+	}
+
+	battleEvents.publish(battleId, BattleEvent{
+		Type:      "start",
+		BattleId:  battleId,
+		Battle:    battle.viewFor(""),
+		Timestamp: battle.UpdatedAt,
+	})
+
+	log.Printf("User %s accepted challenge %s: %s vs %s", user.Username, battleId, teamNames(battle.PlayerTeam), teamNames(team))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ChallengeResponse{Battle: battle.viewFor("computer")})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import "testing"
+
+func testPvPBattle() *BattleState {
+	battle := testAIBattle()
+	battle.UserId = "user-1"
+	battle.OpponentUserId = "user-2"
+	battle.Mode = BattleModePvP
+	battle.CurrentTurn = "player"
+	battle.BattleStatus = "active"
+	return battle
+}
+
+func TestSideOf(t *testing.T) {
+	battle := testPvPBattle()
+	tests := []struct {
+		userId string
+		want   string
+	}{
+		{userId: "user-1", want: "player"},
+		{userId: "user-2", want: "computer"},
+		{userId: "user-3", want: ""},
+		{userId: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.userId, func(t *testing.T) {
+			if got := battle.sideOf(tt.userId); got != tt.want {
+				t.Errorf("sideOf(%q) = %q, want %q", tt.userId, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSubmitChoiceWaitsForBothPlayers(t *testing.T) {
+	battle := testPvPBattle()
+
+	result, err := submitChoice(battle, "player", BattleChoice{Action: "attack", MoveName: "ember"})
+	if err != nil {
+		t.Fatalf("submitChoice(player) error = %v", err)
+	}
+	if result != nil || len(battle.TurnHistory) != 0 {
+		t.Fatalf("turn resolved before the opponent chose")
+	}
+
+	if _, err := submitChoice(battle, "player", BattleChoice{Action: "attack", MoveName: "ember"}); err == nil {
+		t.Error("expected an error when choosing twice in one turn")
+	}
+
+	// Each player only sees their own pending choice
+	if view := battle.viewFor("computer"); len(view.PendingChoices) != 0 {
+		t.Errorf("opponent view has pending choices %v", view.PendingChoices)
+	}
+	if view := battle.viewFor("player"); view.PendingChoices["player"].MoveName != "ember" {
+		t.Errorf("player view pending choices = %v, want ember", view.PendingChoices)
+	}
+
+	result, err = submitChoice(battle, "computer", BattleChoice{Action: "attack", MoveName: "water-gun"})
+	if err != nil {
+		t.Fatalf("submitChoice(computer) error = %v", err)
+	}
+	if result == nil || result.PlayerAction == nil || result.ComputerAction == nil {
+		t.Fatalf("result = %+v, want both actions resolved", result)
+	}
+	if battle.PendingChoices != nil {
+		t.Errorf("PendingChoices = %v, want cleared after the turn", battle.PendingChoices)
+	}
+}
+
+func TestSubmitChoiceRejectsInvalidMoves(t *testing.T) {
+	battle := testPvPBattle()
+
+	if _, err := submitChoice(battle, "computer", BattleChoice{Action: "attack", MoveName: "hydro-pump"}); err == nil {
+		t.Error("expected an error for a move without PP")
+	}
+	if _, ok := battle.PendingChoices["computer"]; ok {
+		t.Error("invalid choice was stored")
+	}
+}
+
+func TestResolveTurnForcesPvPSwitch(t *testing.T) {
+	battle := testPvPBattle()
+	battle.ComputerTeam = append(battle.ComputerTeam, battle.ComputerTeam[0])
+	battle.ComputerTeam[0].CurrentHP = 1
+
+	result := resolveTurn(battle, map[string]BattleChoice{
+		"player":   {Action: "attack", MoveName: "ember"},
+		"computer": {Action: "attack", MoveName: "tackle"},
+	})
+
+	if battle.CurrentTurn != "switch" || !battle.mustSwitch("computer") || !result.SwitchRequired {
+		t.Fatalf("CurrentTurn = %q, ForcedSwitches = %v, want the opponent to switch", battle.CurrentTurn, battle.ForcedSwitches)
+	}
+	if battle.ComputerActive != 0 {
+		t.Errorf("ComputerActive = %d, opponent's replacement was picked automatically", battle.ComputerActive)
+	}
+
+	if _, err := submitChoice(battle, "player", BattleChoice{Action: "attack", MoveName: "ember"}); err == nil {
+		t.Error("expected an error when the player acts during the opponent's forced switch")
+	}
+	if _, err := submitChoice(battle, "computer", BattleChoice{Action: "switch", SwitchTo: 1}); err != nil {
+		t.Fatalf("submitChoice(switch) error = %v", err)
+	}
+	if battle.ComputerActive != 1 || battle.CurrentTurn != "player" {
+		t.Errorf("ComputerActive = %d, CurrentTurn = %q, want 1 and player", battle.ComputerActive, battle.CurrentTurn)
+	}
+}
@@ -0,0 +1,304 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"backend/middleware"
+)
+
+const (
+	ReplayFormatVersion = 1
+	MaxReplayTurns      = 500 // Longest replay the import endpoint will simulate
+	MaxReplayStat       = 999 // Highest HP or stat a replayed Pokemon can have
+	MaxReplayMovePower  = 250
+	MaxReplayMovePP     = 64 // 40 PP raised by PP Ups
+	MaxReplayMoveHits   = 10
+	MinReplayPriority   = -7
+	MaxReplayPriority   = 5
+)
+
+// ReplayTurn holds the choices every side made for one resolved turn or forced switch
+type ReplayTurn struct {
+	Turn    int                     `json:"turn"`
+	Choices map[string]BattleChoice `json:"choices"` // Keyed by side, "player" or "computer"
+}
+
+// BattleReplay is everything needed to re-simulate a battle: the seed, the starting
+// teams and each turn's choices. In PvE battles the computer's choices are
+// re-derived from its strategy, so only the player's choices drive the replay.
+type BattleReplay struct {
+	Version          int             `json:"version"`
+	BattleId         string          `json:"battleId,omitempty"`
+	Mode             string          `json:"mode"`
+	OpponentStrategy string          `json:"opponentStrategy,omitempty"`
+	Seed             int64           `json:"seed"`
+	PlayerTeam       []BattlePokemon `json:"playerTeam"`
+	ComputerTeam     []BattlePokemon `json:"computerTeam"`
+	Turns            []ReplayTurn    `json:"turns"`
+}
+
+type ReplayResponse struct {
+	Replay *BattleReplay `json:"replay,omitempty"`
+	Battle *BattleState  `json:"battle,omitempty"` // Re-simulated battle, including its turn history
+	Error  string        `json:"error,omitempty"`
+}
+
+// exportReplay builds the replay for a battle
+func exportReplay(battle *BattleState) *BattleReplay {
+	return &BattleReplay{
+		Version:          ReplayFormatVersion,
+		BattleId:         battle.BattleId,
+		Mode:             battle.Mode,
+		OpponentStrategy: battle.OpponentStrategy,
+		Seed:             battle.Seed,
+		PlayerTeam:       battle.InitialPlayerTeam,
+		ComputerTeam:     battle.InitialComputerTeam,
+		Turns:            battle.Choices,
+	}
+}
+
+// validateReplayPokemon checks that an imported Pokemon could have come from a real
+// battle, so a crafted replay can't make the simulation run away
+func validateReplayPokemon(pokemon *BattlePokemon) error {
+	if pokemon.Level < MinLevel || pokemon.Level > MaxLevel {
+		return fmt.Errorf("%s: level must be between %d and %d", pokemon.Name, MinLevel, MaxLevel)
+	}
+	if pokemon.MaxHP < 1 || pokemon.MaxHP > MaxReplayStat {
+		return fmt.Errorf("%s: max HP must be between 1 and %d", pokemon.Name, MaxReplayStat)
+	}
+	if pokemon.CurrentHP < 0 || pokemon.CurrentHP > pokemon.MaxHP {
+		return fmt.Errorf("%s: HP must be between 0 and its max HP", pokemon.Name)
+	}
+	for _, stat := range []string{"attack", "defense", "special-attack", "special-defense", "speed"} {
+		if value := pokemon.Stats.get(stat); value < 1 || value > MaxReplayStat {
+			return fmt.Errorf("%s: %s must be between 1 and %d", pokemon.Name, stat, MaxReplayStat)
+		}
+	}
+	stages := pokemon.StatStages
+	for _, stage := range []int{stages.Attack, stages.Defense, stages.SpecialAttack, stages.SpecialDefense, stages.Speed, stages.Accuracy, stages.Evasion} {
+		if stage < -MaxStatStage || stage > MaxStatStage {
+			return fmt.Errorf("%s: stat stages must be between %d and %d", pokemon.Name, -MaxStatStage, MaxStatStage)
+		}
+	}
+	if _, ok := itemEffects[pokemon.Item]; pokemon.Item != "" && !ok {
+		return fmt.Errorf("%s: unknown item %s", pokemon.Name, pokemon.Item)
+	}
+
+	if len(pokemon.Moves) == 0 || len(pokemon.Moves) > MaxMoves {
+		return fmt.Errorf("%s: must know between 1 and %d moves", pokemon.Name, MaxMoves)
+	}
+	for _, move := range pokemon.Moves {
+		if err := validateReplayMove(&move); err != nil {
+			return fmt.Errorf("%s: %s: %w", pokemon.Name, move.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateReplayMove checks an imported move's numbers are within what PokeAPI has
+func validateReplayMove(move *PokemonMove) error {
+	if move.Power < 0 || move.Power > MaxReplayMovePower {
+		return fmt.Errorf("power must be between 0 and %d", MaxReplayMovePower)
+	}
+	if move.PP < 0 || move.PP > MaxReplayMovePP || move.CurrentPP < 0 || move.CurrentPP > MaxReplayMovePP {
+		return fmt.Errorf("PP must be between 0 and %d", MaxReplayMovePP)
+	}
+	if move.MinHits < 0 || move.MaxHits < 0 || move.MaxHits > MaxReplayMoveHits || move.MinHits > max(move.MaxHits, 1) {
+		return fmt.Errorf("hits must be between 1 and %d", MaxReplayMoveHits)
+	}
+	if move.Priority < MinReplayPriority || move.Priority > MaxReplayPriority {
+		return fmt.Errorf("priority must be between %d and %d", MinReplayPriority, MaxReplayPriority)
+	}
+	if move.Drain < -100 || move.Drain > 100 || move.Healing < 0 || move.Healing > 100 {
+		return fmt.Errorf("drain and healing must be percentages")
+	}
+	for _, chance := range []int{move.Accuracy, move.AilmentChance, move.StatChance, move.FlinchChance} {
+		if chance < 0 || chance > 100 {
+			return fmt.Errorf("accuracy and chances must be percentages")
+		}
+	}
+	return nil
+}
+
+// simulateReplay plays a replay's choices from its starting teams and seed
+func simulateReplay(replay *BattleReplay) (*BattleState, error) {
+	if replay.Version != ReplayFormatVersion {
+		return nil, fmt.Errorf("unsupported replay version %d", replay.Version)
+	}
+	if replay.Mode != BattleModePvE && replay.Mode != BattleModePvP {
+		return nil, fmt.Errorf("mode must be %s or %s", BattleModePvE, BattleModePvP)
+	}
+	if _, ok := opponentStrategies[replay.OpponentStrategy]; replay.Mode == BattleModePvE && !ok {
+		return nil, fmt.Errorf("unknown opponent strategy %s", replay.OpponentStrategy)
+	}
+	for _, team := range [][]BattlePokemon{replay.PlayerTeam, replay.ComputerTeam} {
+		if len(team) == 0 || len(team) > MaxTeamSize {
+			return nil, fmt.Errorf("teams must have between 1 and %d Pokemon", MaxTeamSize)
+		}
+		for i := range team {
+			if err := validateReplayPokemon(&team[i]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if len(replay.Turns) > MaxReplayTurns {
+		return nil, fmt.Errorf("replays can have at most %d turns", MaxReplayTurns)
+	}
+
+	battle := &BattleState{
+		BattleId:         replay.BattleId,
+		Mode:             replay.Mode,
+		OpponentStrategy: replay.OpponentStrategy,
+		Seed:             replay.Seed,
+		PlayerTeam:       cloneTeam(replay.PlayerTeam),
+		ComputerTeam:     cloneTeam(replay.ComputerTeam),
+		CurrentTurn:      "player",
+		BattleStatus:     "active",
+		TurnHistory:      []TurnAction{},
+	}
+	applyLeadEffects(battle, "")
+
+	for i, turn := range replay.Turns {
+		if battle.BattleStatus != "active" {
+			return nil, fmt.Errorf("turn %d: the battle has already ended", i+1)
+		}
+
+		if !battle.isPvP() {
+			if _, err := processBattleTurn(battle, turn.Choices["player"]); err != nil {
+				return nil, fmt.Errorf("turn %d: %w", i+1, err)
+			}
+			continue
+		}
+
+		// A forfeit ends the battle without the other side's choice
+		sides := battle.choosingSides()
+		for side, choice := range turn.Choices {
+			if isForfeit(choice) {
+				sides = []string{side}
+			}
+		}
+
+		for _, side := range sides {
+			choice, ok := turn.Choices[side]
+			if !ok {
+				return nil, fmt.Errorf("turn %d: missing choice for %s", i+1, side)
+			}
+			if _, err := submitChoice(battle, side, choice); err != nil {
+				return nil, fmt.Errorf("turn %d: %w", i+1, err)
+			}
+		}
+	}
+	return battle, nil
+}
+
+// ExportReplayHandler returns the replay of a finished battle to one of its players
+func ExportReplayHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ReplayResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ReplayResponse{Error: "Authentication required"})
+		return
+	}
+
+	// Extract battle ID from URL path
+	// Expected format: /battle/{battleId}/replay
+	battleId := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/battle/"), "/replay")
+	if battleId == "" || strings.Contains(battleId, "/") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ReplayResponse{Error: "Battle ID required"})
+		return
+	}
+
+	battle, err := loadBattleState(battleId, user.Sub)
+	if err != nil {
+		log.Printf("Error loading battle state: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(ReplayResponse{Error: "Battle not found"})
+		return
+	}
+
+	// The replay reveals the seed, which would let players predict an active battle
+	if !battle.finished() {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ReplayResponse{Error: "Replays are available once the battle is over"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReplayResponse{Replay: exportReplay(battle)})
+}
+
+// ImportReplayHandler re-simulates an uploaded replay and returns the resulting battle log.
+// Nothing is saved.
+func ImportReplayHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(ReplayResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(ReplayResponse{Error: "Authentication required"})
+		return
+	}
+
+	var replay BattleReplay
+	if err := json.NewDecoder(r.Body).Decode(&replay); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ReplayResponse{Error: "Invalid request body"})
+		return
+	}
+
+	battle, err := simulateReplay(&replay)
+	if err != nil {
+		log.Printf("Error simulating replay: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(ReplayResponse{Error: err.Error()})
+		return
+	}
+
+	log.Printf("User %s replayed %d turns of battle %s", user.Username, len(replay.Turns), replay.BattleId)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReplayResponse{Battle: battle.viewFor("")})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// clearTimestamps blanks the wall-clock timestamps so histories can be compared
+func clearTimestamps(history []TurnAction) []TurnAction {
+	for i := range history {
+		history[i].Timestamp = ""
+	}
+	return history
+}
+
+func TestReplayReproducesBattle(t *testing.T) {
+	battle := testAIBattle()
+	battle.Mode = BattleModePvE
+	battle.OpponentStrategy = "random"
+	battle.Seed = 7
+	battle.CurrentTurn = "player"
+	battle.BattleStatus = "active"
+	battle.InitialPlayerTeam = cloneTeam(battle.PlayerTeam)
+	battle.InitialComputerTeam = cloneTeam(battle.ComputerTeam)
+
+	for turn := 0; turn < 4 && battle.BattleStatus == "active"; turn++ {
+		if _, err := processBattleTurn(battle, BattleChoice{Action: "attack", MoveName: "ember"}); err != nil {
+			t.Fatalf("processBattleTurn() error = %v", err)
+		}
+	}
+
+	// Round trip the replay through JSON like the export and import endpoints do
+	data, err := json.Marshal(exportReplay(battle))
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var replay BattleReplay
+	if err := json.Unmarshal(data, &replay); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	replayed, err := simulateReplay(&replay)
+	if err != nil {
+		t.Fatalf("simulateReplay() error = %v", err)
+	}
+	if !reflect.DeepEqual(clearTimestamps(battle.TurnHistory), clearTimestamps(replayed.TurnHistory)) {
+		t.Error("replayed TurnHistory differs from the original battle")
+	}
+	if replayed.BattleStatus != battle.BattleStatus {
+		t.Errorf("replayed BattleStatus = %q, want %q", replayed.BattleStatus, battle.BattleStatus)
+	}
+}
+
+// replayTeamWith returns a copy of a team with its lead changed
+func replayTeamWith(team []BattlePokemon, change func(*BattlePokemon)) []BattlePokemon {
+	team = cloneTeam(team)
+	change(&team[0])
+	return team
+}
+
+func TestSimulateReplayValidation(t *testing.T) {
+	team := testAIBattle().PlayerTeam
+	tests := []struct {
+		name   string
+		replay BattleReplay
+	}{
+		{
+			name:   "unsupported version",
+			replay: BattleReplay{Version: 99, Mode: BattleModePvP, PlayerTeam: team, ComputerTeam: team},
+		},
+		{
+			name:   "unknown mode",
+			replay: BattleReplay{Version: ReplayFormatVersion, Mode: "doubles", PlayerTeam: team, ComputerTeam: team},
+		},
+		{
+			name:   "unknown strategy",
+			replay: BattleReplay{Version: ReplayFormatVersion, Mode: BattleModePvE, OpponentStrategy: "cheat", PlayerTeam: team, ComputerTeam: team},
+		},
+		{
+			name:   "empty team",
+			replay: BattleReplay{Version: ReplayFormatVersion, Mode: BattleModePvP, PlayerTeam: team},
+		},
+		{
+			name:   "level out of range",
+			replay: BattleReplay{Version: ReplayFormatVersion, Mode: BattleModePvP, PlayerTeam: replayTeamWith(team, func(p *BattlePokemon) { p.Level = 1000 }), ComputerTeam: team},
+		},
+		{
+			name:   "HP above max HP",
+			replay: BattleReplay{Version: ReplayFormatVersion, Mode: BattleModePvP, PlayerTeam: replayTeamWith(team, func(p *BattlePokemon) { p.CurrentHP = p.MaxHP + 1 }), ComputerTeam: team},
+		},
+		{
+			name:   "huge stats",
+			replay: BattleReplay{Version: ReplayFormatVersion, Mode: BattleModePvP, PlayerTeam: replayTeamWith(team, func(p *BattlePokemon) { p.Stats.Attack = 1 << 30 }), ComputerTeam: team},
+		},
+		{
+			name:   "too many hits",
+			replay: BattleReplay{Version: ReplayFormatVersion, Mode: BattleModePvP, PlayerTeam: replayTeamWith(team, func(p *BattlePokemon) { p.Moves[0].MinHits, p.Moves[0].MaxHits = 2, 1000000 }), ComputerTeam: team},
+		},
+		{
+			name:   "unknown item",
+			replay: BattleReplay{Version: ReplayFormatVersion, Mode: BattleModePvP, PlayerTeam: replayTeamWith(team, func(p *BattlePokemon) { p.Item = "master-ball" }), ComputerTeam: team},
+		},
+		{
+			name: "missing PvP choice",
+			replay: BattleReplay{Version: ReplayFormatVersion, Mode: BattleModePvP, PlayerTeam: team, ComputerTeam: team, Turns: []ReplayTurn{
+				{Turn: 1, Choices: map[string]BattleChoice{"player": {Action: "attack", MoveName: "ember"}}},
+			}},
+		},
+		{
+			name: "illegal move",
+			replay: BattleReplay{Version: ReplayFormatVersion, Mode: BattleModePvE, OpponentStrategy: "greedy", PlayerTeam: team, ComputerTeam: team, Turns: []ReplayTurn{
+				{Turn: 1, Choices: map[string]BattleChoice{"player": {Action: "attack", MoveName: "hyper-beam"}}},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := simulateReplay(&tt.replay); err == nil {
+				t.Error("simulateReplay() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"time"
+)
+
+// battleRNG is a battle's seeded random number generator. Its position is saved
+// with the battle, so a reloaded battle continues the same sequence and replaying
+// the same choices from the same seed gives the same outcome.
+type battleRNG struct {
+	*rand.Rand
+	source *rand.PCG
+}
+
+func newBattleRNG(seed int64) *battleRNG {
+	source := rand.NewPCG(uint64(seed), uint64(seed))
+	return &battleRNG{Rand: rand.New(source), source: source}
+}
+
+// newTeamRNG returns a generator for picking the computer's team. It uses a
+// different stream from the battle's RNG, which starts from the bare seed.
+func newTeamRNG(seed int64) *rand.Rand {
+	return rand.New(rand.NewPCG(uint64(seed), ^uint64(seed)))
+}
+
+// newBattleSeed picks a seed for battles that weren't given one
+func newBattleSeed() int64 {
+	return time.Now().UnixNano()
+}
+
+func (r *battleRNG) MarshalJSON() ([]byte, error) {
+	state, err := r.source.MarshalBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode RNG state: %w", err)
+	}
+	return json.Marshal(state)
+}
+
+func (r *battleRNG) UnmarshalJSON(data []byte) error {
+	var state []byte
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+
+	source := &rand.PCG{}
+	if err := source.UnmarshalBinary(state); err != nil {
+		return fmt.Errorf("failed to decode RNG state: %w", err)
+	}
+	r.source = source
+	r.Rand = rand.New(source)
+	return nil
+}
+
+// random returns the battle's RNG, creating it from the seed on first use
+func (b *BattleState) random() *battleRNG {
+	if b.RNG == nil {
+		b.RNG = newBattleRNG(b.Seed)
+	}
+	return b.RNG
+}
@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// playSeededBattle plays the same choices for a few turns, optionally reloading
+// the battle from JSON between turns like the battle store does
+func playSeededBattle(t *testing.T, seed int64, reload bool) []TurnAction {
+	battle := testAIBattle()
+	battle.Seed = seed
+	battle.CurrentTurn = "player"
+	battle.BattleStatus = "active"
+	battle.PlayerTeam[0].Status = StatusParalysis
+
+	for turn := 0; turn < 5 && battle.BattleStatus == "active"; turn++ {
+		if _, err := processBattleTurn(battle, BattleChoice{Action: "attack", MoveName: "ember"}); err != nil {
+			t.Fatalf("processBattleTurn() error = %v", err)
+		}
+
+		if reload {
+			data, err := json.Marshal(battle)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			battle = &BattleState{}
+			if err := json.Unmarshal(data, battle); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+		}
+	}
+
+	return clearTimestamps(battle.TurnHistory)
+}
+
+func TestSeededBattlesAreReproducible(t *testing.T) {
+	first := playSeededBattle(t, 42, false)
+	if len(first) == 0 {
+		t.Fatal("no turns were played")
+	}
+
+	if second := playSeededBattle(t, 42, false); !reflect.DeepEqual(first, second) {
+		t.Error("same seed and choices gave a different TurnHistory")
+	}
+	if reloaded := playSeededBattle(t, 42, true); !reflect.DeepEqual(first, reloaded) {
+		t.Error("reloading the battle between turns changed the TurnHistory")
+	}
+}
+
+func TestViewForHidesSeedUntilBattleEnds(t *testing.T) {
+	battle := &BattleState{Seed: 42, BattleStatus: "active"}
+	battle.random()
+
+	view := battle.viewFor("player")
+	if view.Seed != 0 || view.RNG != nil {
+		t.Errorf("active battle view has Seed = %d, RNG = %v", view.Seed, view.RNG)
+	}
+
+	battle.BattleStatus = "won"
+	if view := battle.viewFor("player"); view.Seed != 42 || view.RNG != nil {
+		t.Errorf("finished battle view has Seed = %d, RNG = %v, want 42 and no RNG", view.Seed, view.RNG)
+	}
+}
@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+
+	"backend/config"
+	"backend/middleware"
+)
+
+const (
+	DefaultSimulationBattles  = 100
+	DefaultSimulationStrategy = "greedy"
+	MaxSimulationTurns        = MaxReplayTurns // Battles still going after this many turns are draws
+)
+
+// SimulationSide is one side of a simulation: a single Pokemon or a team, along
+// with the strategy that plays it
+type SimulationSide struct {
+	PokemonId int `json:"pokemonId,omitempty"` // Single Pokemon, used when TeamIds is empty
+	ChallengeRequest
+	Strategy string `json:"strategy,omitempty"` // "random", "greedy" (default) or "lookahead"
+}
+
+type SimulationRequest struct {
+	Player   SimulationSide `json:"player"`
+	Opponent SimulationSide `json:"opponent"`
+	Battles  int            `json:"battles,omitempty"` // Defaults to 100, capped by SIMULATION_MAX_BATTLES
+	Seed     *int64         `json:"seed,omitempty"`    // Battle i uses seed+i, picked at random if omitted
+}
+
+// MoveStats is how much damage one side's move did across every simulated battle
+type MoveStats struct {
+	Side          string  `json:"side"` // "player" or "opponent"
+	MoveName      string  `json:"moveName"`
+	Uses          int     `json:"uses"`
+	TotalDamage   int     `json:"totalDamage"`
+	AverageDamage float64 `json:"averageDamage"`
+}
+
+type SimulationResult struct {
+	Battles         int         `json:"battles"`
+	Seed            int64       `json:"seed"`
+	PlayerWins      int         `json:"playerWins"`
+	OpponentWins    int         `json:"opponentWins"`
+	Draws           int         `json:"draws"` // Battles that hit the turn limit
+	PlayerWinRate   float64     `json:"playerWinRate"`
+	OpponentWinRate float64     `json:"opponentWinRate"`
+	AverageTurns    float64     `json:"averageTurns"`
+	Moves           []MoveStats `json:"moves"` // Sorted by side, then average damage
+}
+
+type SimulationResponse struct {
+	Result *SimulationResult `json:"result,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// simulationSides maps battle sides to the names the simulation endpoint uses
+var simulationSides = map[string]string{"player": "player", "computer": "opponent"}
+
+// simulationOutcome is the result of one simulated battle
+type simulationOutcome struct {
+	winner  string // "player", "computer" or "" for a draw
+	turns   int
+	history []TurnAction
+}
+
+// simulateBattle plays one battle between two teams without saving it. The player
+// side's choices come from its strategy, the computer's from the battle's.
+func simulateBattle(playerTeam, opponentTeam []BattlePokemon, playerStrategy OpponentStrategy, opponentStrategy string, seed int64) (simulationOutcome, error) {
+	battle := &BattleState{
+		Mode:             BattleModePvE,
+		PlayerTeam:       cloneTeam(playerTeam),
+		ComputerTeam:     cloneTeam(opponentTeam),
+		OpponentStrategy: opponentStrategy,
+		Seed:             seed,
+		CurrentTurn:      "player",
+		BattleStatus:     "active",
+		TurnHistory:      []TurnAction{},
+	}
+	applyLeadEffects(battle, "")
+
+	for turns := 0; battle.BattleStatus == "active"; turns++ {
+		if turns == MaxSimulationTurns {
+			return simulationOutcome{turns: nextTurnNumber(battle) - 1, history: battle.TurnHistory}, nil
+		}
+
+		choice := BattleChoice{Action: "switch", SwitchTo: nextAvailablePokemon(battle.PlayerTeam)}
+		if battle.CurrentTurn != "switch" {
+			choice = playerStrategy.ChooseAction(battle, "player")
+		}
+		if _, err := processBattleTurn(battle, choice); err != nil {
+			return simulationOutcome{}, err
+		}
+	}
+
+	return simulationOutcome{
+		winner:  battle.winningSide(),
+		turns:   nextTurnNumber(battle) - 1,
+		history: battle.TurnHistory,
+	}, nil
+}
+
+var (
+	simulationSlots     chan struct{}
+	simulationSlotsOnce sync.Once
+)
+
+// getSimulationSlots returns the semaphore every simulation request shares, so
+// SIMULATION_CONCURRENCY caps the battles running on the instance as a whole
+func getSimulationSlots() chan struct{} {
+	simulationSlotsOnce.Do(func() {
+		simulationSlots = make(chan struct{}, config.LoadSimulationConfig().Concurrency)
+	})
+	return simulationSlots
+}
+
+// runSimulations plays battles concurrently, each holding one of slots while it runs,
+// and returns the outcomes in seed order. No more battles are started once ctx is
+// done, e.g. because the client went away.
+func runSimulations(ctx context.Context, playerTeam, opponentTeam []BattlePokemon, playerStrategy OpponentStrategy, opponentStrategy string, battles int, seed int64, slots chan struct{}) ([]simulationOutcome, error) {
+	outcomes := make([]simulationOutcome, battles)
+	errs := make([]error, battles)
+
+	var wg sync.WaitGroup
+	for i := 0; i < battles; i++ {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return nil, ctx.Err()
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			outcomes[i], errs[i] = simulateBattle(playerTeam, opponentTeam, playerStrategy, opponentStrategy, seed+int64(i))
+		}(i)
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			return nil, fmt.Errorf("battle %d: %w", i+1, err)
+		}
+	}
+	return outcomes, nil
+}
+
+// summarizeSimulations totals up win rates, battle length and damage per move
+func summarizeSimulations(outcomes []simulationOutcome, seed int64) *SimulationResult {
+	result := &SimulationResult{Battles: len(outcomes), Seed: seed, Moves: []MoveStats{}}
+	if len(outcomes) == 0 {
+		return result
+	}
+
+	moves := make(map[[2]string]*MoveStats)
+	totalTurns := 0
+	for _, outcome := range outcomes {
+		switch outcome.winner {
+		case "player":
+			result.PlayerWins++
+		case "computer":
+			result.OpponentWins++
+		default:
+			result.Draws++
+		}
+		totalTurns += outcome.turns
+
+		for _, action := range outcome.history {
+			if action.Action != "attack" {
+				continue
+			}
+			key := [2]string{simulationSides[action.Actor], action.MoveName}
+			stats, ok := moves[key]
+			if !ok {
+				stats = &MoveStats{Side: key[0], MoveName: key[1]}
+				moves[key] = stats
+			}
+			stats.Uses++
+			stats.TotalDamage += action.Damage
+		}
+	}
+
+	battles := float64(len(outcomes))
+	result.PlayerWinRate = float64(result.PlayerWins) / battles
+	result.OpponentWinRate = float64(result.OpponentWins) / battles
+	result.AverageTurns = float64(totalTurns) / battles
+
+	for _, stats := range moves {
+		stats.AverageDamage = float64(stats.TotalDamage) / float64(stats.Uses)
+		result.Moves = append(result.Moves, *stats)
+	}
+	sort.Slice(result.Moves, func(i, j int) bool {
+		a, b := result.Moves[i], result.Moves[j]
+		if a.Side != b.Side {
+			return a.Side == "player"
+		}
+		if a.AverageDamage != b.AverageDamage {
+			return a.AverageDamage > b.AverageDamage
+		}
+		return a.MoveName < b.MoveName
+	})
+	return result
+}
+
+// fetchSimulationSide validates one side of a simulation request and fetches its team
+func fetchSimulationSide(side SimulationSide) ([]BattlePokemon, string, int, error) {
+	strategy := side.Strategy
+	if strategy == "" {
+		strategy = DefaultSimulationStrategy
+	}
+	if _, ok := opponentStrategies[strategy]; !ok {
+		return nil, "", http.StatusBadRequest, fmt.Errorf("unknown strategy %s", strategy)
+	}
+
+	req := side.ChallengeRequest
+	if len(req.TeamIds) == 0 && side.PokemonId != 0 {
+		req.TeamIds = []int{side.PokemonId}
+	}
+	team, status, err := fetchChallengeTeam(req)
+	if err != nil {
+		return nil, "", status, err
+	}
+	return team, strategy, http.StatusOK, nil
+}
+
+// SimulateBattlesHandler runs many computer-vs-computer battles between two teams
+// and reports how they went. Nothing is saved.
+func SimulateBattlesHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(SimulationResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(SimulationResponse{Error: "Authentication required"})
+		return
+	}
+
+	var req SimulationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(SimulationResponse{Error: "Invalid request body"})
+		return
+	}
+
+	simulationConfig := config.LoadSimulationConfig()
+	battles := req.Battles
+	if battles == 0 {
+		battles = DefaultSimulationBattles
+	}
+	if battles < 1 || battles > simulationConfig.MaxBattles {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(SimulationResponse{Error: fmt.Sprintf("Battles must be between 1 and %d", simulationConfig.MaxBattles)})
+		return
+	}
+
+	playerTeam, playerStrategy, status, err := fetchSimulationSide(req.Player)
+	if err != nil {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(SimulationResponse{Error: fmt.Sprintf("Player: %s", err.Error())})
+		return
+	}
+	opponentTeam, opponentStrategy, status, err := fetchSimulationSide(req.Opponent)
+	if err != nil {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(SimulationResponse{Error: fmt.Sprintf("Opponent: %s", err.Error())})
+		return
+	}
+
+	seed := newBattleSeed()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	outcomes, err := runSimulations(r.Context(), playerTeam, opponentTeam, opponentStrategies[playerStrategy], opponentStrategy, battles, seed, getSimulationSlots())
+	if errors.Is(err, context.Canceled) {
+		log.Printf("User %s left before their simulation finished", user.Username)
+		return
+	}
+	if err != nil {
+		log.Printf("Error simulating battles: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(SimulationResponse{Error: "Failed to simulate battles"})
+		return
+	}
+
+	result := summarizeSimulations(outcomes, seed)
+	log.Printf("User %s simulated %d battles of %s vs %s: %d-%d-%d", user.Username, battles, teamNames(playerTeam), teamNames(opponentTeam), result.PlayerWins, result.OpponentWins, result.Draws)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SimulationResponse{Result: result})
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestSimulateBattle(t *testing.T) {
+	battle := testAIBattle()
+	outcome, err := simulateBattle(battle.PlayerTeam, battle.ComputerTeam, greedyStrategy{}, "greedy", 1)
+	if err != nil {
+		t.Fatalf("simulateBattle() error = %v", err)
+	}
+	if outcome.winner == "" || outcome.turns == 0 {
+		t.Errorf("outcome = %+v, want a finished battle", outcome)
+	}
+	if battle.PlayerTeam[0].CurrentHP != 150 || battle.ComputerTeam[0].Moves[0].CurrentPP != 35 {
+		t.Error("simulateBattle() changed the teams it was given")
+	}
+}
+
+func TestRunSimulationsIsDeterministic(t *testing.T) {
+	battle := testAIBattle()
+	run := func(concurrency int) *SimulationResult {
+		outcomes, err := runSimulations(context.Background(), battle.PlayerTeam, battle.ComputerTeam, randomStrategy{}, "random", 20, 7, make(chan struct{}, concurrency))
+		if err != nil {
+			t.Fatalf("runSimulations() error = %v", err)
+		}
+		return summarizeSimulations(outcomes, 7)
+	}
+
+	serial, concurrent := run(1), run(8)
+	if !reflect.DeepEqual(serial, concurrent) {
+		t.Errorf("results differ with concurrency:\n%+v\n%+v", serial, concurrent)
+	}
+	if serial.PlayerWins+serial.OpponentWins+serial.Draws != 20 {
+		t.Errorf("result = %+v, want 20 battles accounted for", serial)
+	}
+}
+
+func TestRunSimulationsStopsWhenCanceled(t *testing.T) {
+	battle := testAIBattle()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Every slot is taken by other requests, so nothing can start before the cancel is seen
+	slots := make(chan struct{}, 1)
+	slots <- struct{}{}
+	if _, err := runSimulations(ctx, battle.PlayerTeam, battle.ComputerTeam, randomStrategy{}, "random", 20, 7, slots); !errors.Is(err, context.Canceled) {
+		t.Errorf("runSimulations() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestSummarizeSimulations(t *testing.T) {
+	outcomes := []simulationOutcome{
+		{winner: "player", turns: 3, history: []TurnAction{
+			{Actor: "player", Action: "attack", MoveName: "ember", Damage: 40},
+			{Actor: "computer", Action: "attack", MoveName: "tackle", Damage: 20},
+			{Actor: "player", Action: "attack", MoveName: "ember", Damage: 60},
+			{Actor: "computer", Action: "status", MoveName: "burn", Damage: 9},
+		}},
+		{winner: "computer", turns: 5},
+		{turns: MaxSimulationTurns},
+		{winner: "player", turns: 4},
+	}
+
+	result := summarizeSimulations(outcomes, 1)
+	if result.PlayerWins != 2 || result.OpponentWins != 1 || result.Draws != 1 {
+		t.Errorf("wins = %d-%d-%d, want 2-1-1", result.PlayerWins, result.OpponentWins, result.Draws)
+	}
+	if result.PlayerWinRate != 0.5 || result.OpponentWinRate != 0.25 {
+		t.Errorf("win rates = %v, %v, want 0.5 and 0.25", result.PlayerWinRate, result.OpponentWinRate)
+	}
+	if want := float64(3+5+MaxSimulationTurns+4) / 4; result.AverageTurns != want {
+		t.Errorf("average turns = %v, want %v", result.AverageTurns, want)
+	}
+
+	want := []MoveStats{
+		{Side: "player", MoveName: "ember", Uses: 2, TotalDamage: 100, AverageDamage: 50},
+		{Side: "opponent", MoveName: "tackle", Uses: 1, TotalDamage: 20, AverageDamage: 20},
+	}
+	if !reflect.DeepEqual(result.Moves, want) {
+		t.Errorf("moves = %+v, want %+v", result.Moves, want)
+	}
+}
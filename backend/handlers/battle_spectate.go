@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"backend/middleware"
+)
+
+const SpectatorTokenBytes = 16
+
+type SpectatorLinkResponse struct {
+	Token string `json:"token,omitempty"`
+	Path  string `json:"path,omitempty"` // Relative link for watching, e.g. /spectate/{battleId}?token=...
+	Error string `json:"error,omitempty"`
+}
+
+// newSpectatorToken returns a random hex token
+func newSpectatorToken() (string, error) {
+	token := make([]byte, SpectatorTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return hex.EncodeToString(token), nil
+}
+
+// loadSpectatedBattle loads a battle for a spectator, checking their token
+func loadSpectatedBattle(battleId, token string) (*BattleState, error) {
+	battle, err := getBattleStore().Load(battleId)
+	if err != nil {
+		return nil, err
+	}
+
+	if token == "" || battle.SpectatorToken == "" ||
+		subtle.ConstantTimeCompare([]byte(token), []byte(battle.SpectatorToken)) != 1 {
+		return nil, ErrBattleNotFound
+	}
+	return battle, nil
+}
+
+// CreateSpectatorLinkHandler lets the battle owner mint a read-only spectator token.
+// Minting a new token revokes the previous one.
+func CreateSpectatorLinkHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(SpectatorLinkResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(SpectatorLinkResponse{Error: "Authentication required"})
+		return
+	}
+
+	// Extract battle ID from URL path
+	// Expected format: /battle/{battleId}/spectate
+	battleId := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/battle/"), "/spectate")
+	if battleId == "" || strings.Contains(battleId, "/") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(SpectatorLinkResponse{Error: "Battle ID required"})
+		return
+	}
+
+	battle, err := loadBattleState(battleId, user.Sub)
+	if err != nil || battle.UserId != user.Sub {
+		log.Printf("Error loading battle state: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(SpectatorLinkResponse{Error: "Battle not found"})
+		return
+	}
+
+	token, err := newSpectatorToken()
+	if err != nil {
+		log.Printf("Error creating spectator token: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(SpectatorLinkResponse{Error: "Failed to create spectator link"})
+		return
+	}
+	battle.SpectatorToken = token
+
+	if err := saveBattleState(battle); err != nil {
+		log.Printf("Error saving battle state: %v", err)
+		if errors.Is(err, ErrBattleConflict) {
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(SpectatorLinkResponse{Error: "Battle was updated by another request, please try again"})
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(SpectatorLinkResponse{Error: "Failed to save battle state"})
+		return
+	}
+
+	log.Printf("User %s created spectator link for battle %s", user.Username, battleId)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(SpectatorLinkResponse{
+		Token: token,
+		Path:  fmt.Sprintf("/spectate/%s?token=%s", battleId, token),
+	})
+}
+
+// SpectateBattleHandler returns a battle's state and history to anyone holding its
+// spectator token. Spectators can't make moves and never see pending choices.
+func SpectateBattleHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(GetBattleResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Extract battle ID from URL path
+	// Expected format: /spectate/{battleId} or /spectate/{battleId}/events
+	path := strings.TrimPrefix(r.URL.Path, "/spectate/")
+	stream := strings.HasSuffix(path, "/events")
+	battleId := strings.TrimSuffix(path, "/events")
+	if battleId == "" || strings.Contains(battleId, "/") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(GetBattleResponse{Error: "Battle ID required"})
+		return
+	}
+
+	battle, err := loadSpectatedBattle(battleId, r.URL.Query().Get("token"))
+	if err != nil {
+		log.Printf("Error loading spectated battle: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(GetBattleResponse{Error: "Battle not found"})
+		return
+	}
+
+	if stream {
+		streamBattle(w, r, battle, "")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(GetBattleResponse{Battle: battle.viewFor("")})
+}
@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLoadSpectatedBattle(t *testing.T) {
+	token, err := newSpectatorToken()
+	if err != nil {
+		t.Fatalf("newSpectatorToken() error = %v", err)
+	}
+	if len(token) != SpectatorTokenBytes*2 {
+		t.Errorf("token length = %d, want %d", len(token), SpectatorTokenBytes*2)
+	}
+
+	battle := &BattleState{BattleId: "spectated-battle", UserId: "owner", SpectatorToken: token}
+	if err := getBattleStore().Save(battle); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	if err := getBattleStore().Save(&BattleState{BattleId: "private-battle", UserId: "owner"}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		battleId string
+		token    string
+		wantErr  bool
+	}{
+		{name: "valid token", battleId: "spectated-battle", token: token, wantErr: false},
+		{name: "wrong token", battleId: "spectated-battle", token: "not-the-token", wantErr: true},
+		{name: "missing token", battleId: "spectated-battle", token: "", wantErr: true},
+		{name: "no link minted", battleId: "private-battle", token: "", wantErr: true},
+		{name: "unknown battle", battleId: "missing", token: token, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadSpectatedBattle(tt.battleId, tt.token)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadSpectatedBattle() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrBattleNotFound) {
+				t.Errorf("loadSpectatedBattle() error = %v, want ErrBattleNotFound", err)
+			}
+		})
+	}
+}
+
+func TestViewForHidesSpectatorToken(t *testing.T) {
+	battle := &BattleState{SpectatorToken: "secret"}
+
+	if view := battle.viewFor("player"); view.SpectatorToken != "secret" {
+		t.Error("owner view is missing the spectator token")
+	}
+	for _, viewer := range []string{"computer", ""} {
+		if view := battle.viewFor(viewer); view.SpectatorToken != "" {
+			t.Errorf("viewFor(%q) exposes the spectator token", viewer)
+		}
+	}
+}
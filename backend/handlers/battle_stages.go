@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+)
+
+const (
+	MinStatStage = -6
+	MaxStatStage = 6
+)
+
+// StatStages tracks the in-battle stat modifiers of a Pokemon
+type StatStages struct {
+	Attack         int `json:"attack"`
+	Defense        int `json:"defense"`
+	SpecialAttack  int `json:"specialAttack"`
+	SpecialDefense int `json:"specialDefense"`
+	Speed          int `json:"speed"`
+	Accuracy       int `json:"accuracy"`
+	Evasion        int `json:"evasion"`
+}
+
+// StatChange is a stage change a move applies, using PokeAPI stat names
+type StatChange struct {
+	Stat   string `json:"stat"`   // e.g. "attack", "special-defense", "accuracy"
+	Change int    `json:"change"` // Number of stages, between -6 and +6
+}
+
+// selfDebuffMoves are damaging moves whose stat drops hit the user rather than the target.
+// PokeAPI reports these with the opponent as the move target.
+var selfDebuffMoves = map[string]bool{
+	"close-combat":    true,
+	"superpower":      true,
+	"overheat":        true,
+	"draco-meteor":    true,
+	"leaf-storm":      true,
+	"psycho-boost":    true,
+	"fleur-cannon":    true,
+	"hammer-arm":      true,
+	"ice-hammer":      true,
+	"v-create":        true,
+	"clanging-scales": true,
+	"dragon-ascent":   true,
+	"headlong-rush":   true,
+	"make-it-rain":    true,
+	"armor-cannon":    true,
+	"spin-out":        true,
+}
+
+// stage returns a pointer to the stage for a PokeAPI stat name, or nil if unknown
+func (s *StatStages) stage(stat string) *int {
+	switch stat {
+	case "attack":
+		return &s.Attack
+	case "defense":
+		return &s.Defense
+	case "special-attack":
+		return &s.SpecialAttack
+	case "special-defense":
+		return &s.SpecialDefense
+	case "speed":
+		return &s.Speed
+	case "accuracy":
+		return &s.Accuracy
+	case "evasion":
+		return &s.Evasion
+	}
+	return nil
+}
+
+// statStageMultiplier returns the multiplier for attack, defense, special and speed stages
+func statStageMultiplier(stage int) float64 {
+	stage = clampStage(stage)
+	if stage >= 0 {
+		return float64(2+stage) / 2
+	}
+	return 2 / float64(2-stage)
+}
+
+// accuracyStageMultiplier returns the multiplier for combined accuracy and evasion stages
+func accuracyStageMultiplier(stage int) float64 {
+	stage = clampStage(stage)
+	if stage >= 0 {
+		return float64(3+stage) / 3
+	}
+	return 3 / float64(3-stage)
+}
+
+func clampStage(stage int) int {
+	if stage < MinStatStage {
+		return MinStatStage
+	}
+	if stage > MaxStatStage {
+		return MaxStatStage
+	}
+	return stage
+}
+
+// statChangeTarget decides whether a move's stat changes apply to the user or the target
+func statChangeTarget(moveName, damageClass, pokeAPITarget string, changes []StatChange) string {
+	if damageClass == "status" {
+		switch pokeAPITarget {
+		case "user", "user-and-allies", "users-field", "user-or-ally", "ally":
+			return "user"
+		}
+		return "target"
+	}
+
+	// Damaging moves only ever raise the user's stats
+	if selfDebuffMoves[moveName] {
+		return "user"
+	}
+	for _, change := range changes {
+		if change.Change < 0 {
+			return "target"
+		}
+	}
+	return "user"
+}
+
+// rollStatChanges decides whether a move's stat changes trigger
+func rollStatChanges(rng *rand.Rand, move *PokemonMove) bool {
+	if len(move.StatChanges) == 0 {
+		return false
+	}
+	if move.StatChance <= 0 {
+		return true
+	}
+	return rng.IntN(100) < move.StatChance
+}
+
+// applyStatChanges adjusts the stages of the affected Pokemon and returns the changes
+// actually applied along with their battle log messages
+func applyStatChanges(pokemon *BattlePokemon, changes []StatChange) ([]StatChange, []string) {
+	var applied []StatChange
+	var messages []string
+
+	for _, change := range changes {
+		stage := pokemon.StatStages.stage(change.Stat)
+		if stage == nil || change.Change == 0 {
+			continue
+		}
+
+		statName := strings.ReplaceAll(change.Stat, "-", " ")
+		newStage := clampStage(*stage + change.Change)
+		if newStage == *stage {
+			direction := "higher"
+			if change.Change < 0 {
+				direction = "lower"
+			}
+			messages = append(messages, fmt.Sprintf("%s's %s won't go any %s!", pokemon.Name, statName, direction))
+			continue
+		}
+
+		delta := newStage - *stage
+		*stage = newStage
+		applied = append(applied, StatChange{Stat: change.Stat, Change: delta})
+		messages = append(messages, fmt.Sprintf("%s's %s %s!", pokemon.Name, statName, statChangeVerb(delta)))
+	}
+
+	return applied, messages
+}
+
+func statChangeVerb(delta int) string {
+	switch {
+	case delta >= 3:
+		return "rose drastically"
+	case delta == 2:
+		return "rose sharply"
+	case delta == 1:
+		return "rose"
+	case delta == -1:
+		return "fell"
+	case delta == -2:
+		return "harshly fell"
+	default:
+		return "severely fell"
+	}
+}
@@ -0,0 +1,129 @@
+package handlers
+
+import "testing"
+
+func TestStatStageMultiplier(t *testing.T) {
+	tests := []struct {
+		stage int
+		want  float64
+	}{
+		{stage: -6, want: 0.25},
+		{stage: -1, want: 2.0 / 3},
+		{stage: 0, want: 1},
+		{stage: 2, want: 2},
+		{stage: 6, want: 4},
+		{stage: 8, want: 4},
+	}
+
+	for _, tt := range tests {
+		if result := statStageMultiplier(tt.stage); result != tt.want {
+			t.Errorf("statStageMultiplier(%d) = %v, want %v", tt.stage, result, tt.want)
+		}
+	}
+}
+
+func TestAccuracyStageMultiplier(t *testing.T) {
+	tests := []struct {
+		stage int
+		want  float64
+	}{
+		{stage: -6, want: 1.0 / 3},
+		{stage: 0, want: 1},
+		{stage: 6, want: 3},
+	}
+
+	for _, tt := range tests {
+		if result := accuracyStageMultiplier(tt.stage); result != tt.want {
+			t.Errorf("accuracyStageMultiplier(%d) = %v, want %v", tt.stage, result, tt.want)
+		}
+	}
+}
+
+func TestApplyStatChanges(t *testing.T) {
+	pokemon := &BattlePokemon{Name: "test"}
+	pokemon.StatStages.Attack = 5
+
+	applied, messages := applyStatChanges(pokemon, []StatChange{
+		{Stat: "attack", Change: 2},
+		{Stat: "defense", Change: -1},
+	})
+
+	if pokemon.StatStages.Attack != MaxStatStage {
+		t.Errorf("Attack stage = %d, want %d", pokemon.StatStages.Attack, MaxStatStage)
+	}
+	if pokemon.StatStages.Defense != -1 {
+		t.Errorf("Defense stage = %d, want -1", pokemon.StatStages.Defense)
+	}
+	if len(applied) != 2 || applied[0].Change != 1 {
+		t.Errorf("applied = %+v, want attack +1 and defense -1", applied)
+	}
+	if len(messages) != 2 {
+		t.Errorf("got %d messages, want 2", len(messages))
+	}
+
+	applied, _ = applyStatChanges(pokemon, []StatChange{{Stat: "attack", Change: 1}})
+	if len(applied) != 0 {
+		t.Errorf("applied = %+v at max stage, want none", applied)
+	}
+}
+
+func TestStatChangeTarget(t *testing.T) {
+	tests := []struct {
+		name        string
+		moveName    string
+		damageClass string
+		target      string
+		changes     []StatChange
+		want        string
+	}{
+		{
+			name:        "swords dance boosts user",
+			moveName:    "swords-dance",
+			damageClass: "status",
+			target:      "user",
+			changes:     []StatChange{{Stat: "attack", Change: 2}},
+			want:        "user",
+		},
+		{
+			name:        "growl lowers target",
+			moveName:    "growl",
+			damageClass: "status",
+			target:      "all-opponents",
+			changes:     []StatChange{{Stat: "attack", Change: -1}},
+			want:        "target",
+		},
+		{
+			name:        "acid lowers target",
+			moveName:    "acid",
+			damageClass: "special",
+			target:      "all-opponents",
+			changes:     []StatChange{{Stat: "special-defense", Change: -1}},
+			want:        "target",
+		},
+		{
+			name:        "close combat lowers user",
+			moveName:    "close-combat",
+			damageClass: "physical",
+			target:      "selected-pokemon",
+			changes:     []StatChange{{Stat: "defense", Change: -1}, {Stat: "special-defense", Change: -1}},
+			want:        "user",
+		},
+		{
+			name:        "flame charge boosts user",
+			moveName:    "flame-charge",
+			damageClass: "physical",
+			target:      "selected-pokemon",
+			changes:     []StatChange{{Stat: "speed", Change: 1}},
+			want:        "user",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := statChangeTarget(tt.moveName, tt.damageClass, tt.target, tt.changes)
+			if result != tt.want {
+				t.Errorf("statChangeTarget() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
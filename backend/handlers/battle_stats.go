@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	DefaultBattleLevel = 50
+	MinLevel           = 1
+	MaxLevel           = 100
+	MaxIV              = 31
+	MaxEV              = 252
+	MaxTotalEVs        = 510
+	DefaultNature      = "hardy"
+)
+
+// PokemonSpread customizes how a Pokemon's actual stats are calculated.
+// Zero values fall back to the battle level, perfect IVs, no EVs and a neutral nature.
+type PokemonSpread struct {
+	Level  int           `json:"level,omitempty"`
+	IVs    *PokemonStats `json:"ivs,omitempty"`
+	EVs    *PokemonStats `json:"evs,omitempty"`
+	Nature string        `json:"nature,omitempty"`
+}
+
+// natureModifiers maps each nature to the stat it raises and the stat it lowers by 10%.
+// Neutral natures raise and lower the same stat.
+var natureModifiers = map[string][2]string{
+	"hardy":   {"attack", "attack"},
+	"lonely":  {"attack", "defense"},
+	"brave":   {"attack", "speed"},
+	"adamant": {"attack", "special-attack"},
+	"naughty": {"attack", "special-defense"},
+	"bold":    {"defense", "attack"},
+	"docile":  {"defense", "defense"},
+	"relaxed": {"defense", "speed"},
+	"impish":  {"defense", "special-attack"},
+	"lax":     {"defense", "special-defense"},
+	"timid":   {"speed", "attack"},
+	"hasty":   {"speed", "defense"},
+	"serious": {"speed", "speed"},
+	"jolly":   {"speed", "special-attack"},
+	"naive":   {"speed", "special-defense"},
+	"modest":  {"special-attack", "attack"},
+	"mild":    {"special-attack", "defense"},
+	"quiet":   {"special-attack", "speed"},
+	"bashful": {"special-attack", "special-attack"},
+	"rash":    {"special-attack", "special-defense"},
+	"calm":    {"special-defense", "attack"},
+	"gentle":  {"special-defense", "defense"},
+	"sassy":   {"special-defense", "speed"},
+	"careful": {"special-defense", "special-attack"},
+	"quirky":  {"special-defense", "special-defense"},
+}
+
+// defaultIVs returns a perfect IV spread
+func defaultIVs() PokemonStats {
+	return PokemonStats{HP: MaxIV, Attack: MaxIV, Defense: MaxIV, SpecialAttack: MaxIV, SpecialDefense: MaxIV, Speed: MaxIV}
+}
+
+// withDefaults fills in any unset spread values
+func (s PokemonSpread) withDefaults(level int) PokemonSpread {
+	if s.Level == 0 {
+		s.Level = level
+	}
+	if s.IVs == nil {
+		ivs := defaultIVs()
+		s.IVs = &ivs
+	}
+	if s.EVs == nil {
+		s.EVs = &PokemonStats{}
+	}
+	if s.Nature == "" {
+		s.Nature = DefaultNature
+	}
+	s.Nature = strings.ToLower(s.Nature)
+	return s
+}
+
+// validate checks a spread that has already had its defaults filled in
+func (s PokemonSpread) validate() error {
+	if s.Level < MinLevel || s.Level > MaxLevel {
+		return fmt.Errorf("level must be between %d and %d", MinLevel, MaxLevel)
+	}
+	if _, ok := natureModifiers[s.Nature]; !ok {
+		return fmt.Errorf("unknown nature %s", s.Nature)
+	}
+
+	total := 0
+	for _, stat := range []string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"} {
+		iv, ev := s.IVs.get(stat), s.EVs.get(stat)
+		if iv < 0 || iv > MaxIV {
+			return fmt.Errorf("%s IV must be between 0 and %d", stat, MaxIV)
+		}
+		if ev < 0 || ev > MaxEV {
+			return fmt.Errorf("%s EV must be between 0 and %d", stat, MaxEV)
+		}
+		total += ev
+	}
+	if total > MaxTotalEVs {
+		return fmt.Errorf("EVs can total at most %d", MaxTotalEVs)
+	}
+	return nil
+}
+
+// get returns a stat by its PokeAPI name
+func (s PokemonStats) get(stat string) int {
+	switch stat {
+	case "hp":
+		return s.HP
+	case "attack":
+		return s.Attack
+	case "defense":
+		return s.Defense
+	case "special-attack":
+		return s.SpecialAttack
+	case "special-defense":
+		return s.SpecialDefense
+	case "speed":
+		return s.Speed
+	}
+	return 0
+}
+
+// buildSpreads returns one validated spread per team member, applying overrides in team order
+func buildSpreads(teamSize int, level int, overrides []PokemonSpread) ([]PokemonSpread, error) {
+	if level == 0 {
+		level = DefaultBattleLevel
+	}
+	if len(overrides) > teamSize {
+		return nil, fmt.Errorf("got %d spreads for a team of %d", len(overrides), teamSize)
+	}
+
+	spreads := make([]PokemonSpread, teamSize)
+	for i := range spreads {
+		if i < len(overrides) {
+			spreads[i] = overrides[i]
+		}
+		spreads[i] = spreads[i].withDefaults(level)
+		if err := spreads[i].validate(); err != nil {
+			return nil, fmt.Errorf("team member %d: %w", i+1, err)
+		}
+	}
+	return spreads, nil
+}
+
+// calculateStats computes actual stats from base stats using the standard formulas
+func calculateStats(base PokemonStats, spread PokemonSpread) PokemonStats {
+	level := spread.Level
+	raised, lowered := natureModifiers[spread.Nature][0], natureModifiers[spread.Nature][1]
+
+	stat := func(name string) int {
+		value := (2*base.get(name)+spread.IVs.get(name)+spread.EVs.get(name)/4)*level/100 + 5
+		if raised != lowered {
+			switch name {
+			case raised:
+				value = value * 110 / 100
+			case lowered:
+				value = value * 90 / 100
+			}
+		}
+		return value
+	}
+
+	return PokemonStats{
+		HP:             (2*base.HP+spread.IVs.HP+spread.EVs.HP/4)*level/100 + level + 10,
+		Attack:         stat("attack"),
+		Defense:        stat("defense"),
+		SpecialAttack:  stat("special-attack"),
+		SpecialDefense: stat("special-defense"),
+		Speed:          stat("speed"),
+	}
+}
+
+// applySpread sets a Pokemon's level, spread and actual stats and fully heals it
+func applySpread(pokemon *BattlePokemon, spread PokemonSpread) {
+	pokemon.Level = spread.Level
+	pokemon.IVs = *spread.IVs
+	pokemon.EVs = *spread.EVs
+	pokemon.Nature = spread.Nature
+	pokemon.Stats = calculateStats(pokemon.BaseStats, spread)
+	pokemon.MaxHP = pokemon.Stats.HP
+	pokemon.CurrentHP = pokemon.MaxHP
+}
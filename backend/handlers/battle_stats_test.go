@@ -0,0 +1,70 @@
+package handlers
+
+import "testing"
+
+func TestCalculateStats(t *testing.T) {
+	// Bulbapedia's worked example: a level 78 Adamant Garchomp
+	base := PokemonStats{HP: 108, Attack: 130, Defense: 95, SpecialAttack: 80, SpecialDefense: 85, Speed: 102}
+	spread := PokemonSpread{
+		Level:  78,
+		IVs:    &PokemonStats{HP: 24, Attack: 12, Defense: 30, SpecialAttack: 16, SpecialDefense: 23, Speed: 5},
+		EVs:    &PokemonStats{HP: 74, Attack: 190, Defense: 91, SpecialAttack: 48, SpecialDefense: 84, Speed: 23},
+		Nature: "adamant",
+	}
+	want := PokemonStats{HP: 289, Attack: 278, Defense: 193, SpecialAttack: 135, SpecialDefense: 171, Speed: 171}
+
+	if result := calculateStats(base, spread); result != want {
+		t.Errorf("calculateStats() = %+v, want %+v", result, want)
+	}
+}
+
+func TestBuildSpreads(t *testing.T) {
+	tests := []struct {
+		name      string
+		teamSize  int
+		level     int
+		overrides []PokemonSpread
+		wantErr   bool
+	}{
+		{name: "defaults", teamSize: 2, level: 0},
+		{name: "custom level", teamSize: 1, level: 5},
+		{name: "override nature", teamSize: 2, level: 100, overrides: []PokemonSpread{{Nature: "Modest"}}},
+		{name: "level too high", teamSize: 1, level: 101, wantErr: true},
+		{name: "unknown nature", teamSize: 1, overrides: []PokemonSpread{{Nature: "grumpy"}}, wantErr: true},
+		{name: "iv out of range", teamSize: 1, overrides: []PokemonSpread{{IVs: &PokemonStats{HP: 32}}}, wantErr: true},
+		{name: "ev total too high", teamSize: 1, overrides: []PokemonSpread{{EVs: &PokemonStats{HP: 252, Attack: 252, Speed: 252}}}, wantErr: true},
+		{name: "too many spreads", teamSize: 1, overrides: []PokemonSpread{{}, {}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spreads, err := buildSpreads(tt.teamSize, tt.level, tt.overrides)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("buildSpreads() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(spreads) != tt.teamSize {
+				t.Errorf("buildSpreads() returned %d spreads, want %d", len(spreads), tt.teamSize)
+			}
+		})
+	}
+}
+
+func TestApplySpreadLevels(t *testing.T) {
+	low := &BattlePokemon{BaseStats: PokemonStats{HP: 35, Attack: 55, Defense: 40, SpecialAttack: 50, SpecialDefense: 50, Speed: 90}}
+	high := &BattlePokemon{BaseStats: low.BaseStats}
+
+	lowSpread, _ := buildSpreads(1, 5, nil)
+	highSpread, _ := buildSpreads(1, 100, nil)
+	applySpread(low, lowSpread[0])
+	applySpread(high, highSpread[0])
+
+	if low.Level != 5 || high.Level != 100 {
+		t.Fatalf("levels = %d and %d, want 5 and 100", low.Level, high.Level)
+	}
+	if low.MaxHP >= high.MaxHP || low.Stats.Speed >= high.Stats.Speed {
+		t.Errorf("level 5 stats %+v should be lower than level 100 stats %+v", low.Stats, high.Stats)
+	}
+	if high.CurrentHP != high.MaxHP {
+		t.Errorf("CurrentHP = %d, want full HP %d", high.CurrentHP, high.MaxHP)
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"fmt"
+	"math/rand/v2"
+)
+
+// Non-volatile status conditions, named after PokeAPI's move ailments
+const (
+	StatusBurn      = "burn"
+	StatusPoison    = "poison"
+	StatusParalysis = "paralysis"
+	StatusSleep     = "sleep"
+	StatusFreeze    = "freeze"
+)
+
+const (
+	BurnDamageFraction     = 16 // Burn deals 1/16 of max HP each turn
+	PoisonDamageFraction   = 8  // Poison deals 1/8 of max HP each turn
+	FullParalysisChance    = 0.25
+	ThawChance             = 0.2
+	MinSleepTurns          = 1
+	MaxSleepTurns          = 3
+	ParalysisSpeedModifier = 0.5
+)
+
+// statusImmunities lists the types that can never receive a status
+var statusImmunities = map[string][]string{
+	StatusBurn:      {"fire"},
+	StatusPoison:    {"poison", "steel"},
+	StatusParalysis: {"electric"},
+	StatusFreeze:    {"ice"},
+}
+
+// isSupportedStatus reports whether the battle engine models the given ailment
+func isSupportedStatus(status string) bool {
+	switch status {
+	case StatusBurn, StatusPoison, StatusParalysis, StatusSleep, StatusFreeze:
+		return true
+	}
+	return false
+}
+
+// rollAilment decides whether a move's secondary ailment triggers
+func rollAilment(rng *rand.Rand, move *PokemonMove) bool {
+	if move.Ailment == "" {
+		return false
+	}
+	if move.AilmentChance <= 0 {
+		// Status moves like Thunder Wave report a chance of 0 but always apply
+		return move.DamageClass == "status"
+	}
+	return rng.IntN(100) < move.AilmentChance
+}
+
+// statusMoveImmune reports whether the target's type is immune to a status move that
+// inflicts an ailment, e.g. Thunder Wave against Ground types or Toxic against Steel types
+func statusMoveImmune(move *PokemonMove, defender *BattlePokemon) bool {
+	return move.Ailment != "" && typeEffectiveness(move.Type, defender.Types) == 0
+}
+
+// inflictStatus applies a status to the Pokemon unless it already has one or is immune
+func inflictStatus(rng *rand.Rand, pokemon *BattlePokemon, status string) bool {
+	if pokemon.Status != "" || pokemon.CurrentHP <= 0 || !isSupportedStatus(status) {
+		return false
+	}
+	for _, immuneType := range statusImmunities[status] {
+		if hasType(pokemon, immuneType) {
+			return false
+		}
+	}
+
+	pokemon.Status = status
+	if status == StatusSleep {
+		pokemon.SleepTurns = MinSleepTurns + rng.IntN(MaxSleepTurns-MinSleepTurns+1)
+	}
+	return true
+}
+
+// statusInflictedMessage returns the battle log text for a newly applied status
+func statusInflictedMessage(pokemon *BattlePokemon) string {
+	switch pokemon.Status {
+	case StatusBurn:
+		return fmt.Sprintf("%s was burned!", pokemon.Name)
+	case StatusPoison:
+		return fmt.Sprintf("%s was poisoned!", pokemon.Name)
+	case StatusParalysis:
+		return fmt.Sprintf("%s is paralyzed! It may be unable to move!", pokemon.Name)
+	case StatusSleep:
+		return fmt.Sprintf("%s fell asleep!", pokemon.Name)
+	case StatusFreeze:
+		return fmt.Sprintf("%s was frozen solid!", pokemon.Name)
+	}
+	return ""
+}
+
+// checkStatusBeforeMove resolves sleep, freeze and paralysis before a Pokemon acts.
+// It returns the status action to log (if any) and whether the Pokemon may still move.
+func checkStatusBeforeMove(rng *rand.Rand, pokemon *BattlePokemon, actor string, turnNumber int, timestamp string) (*TurnAction, bool) {
+	action := &TurnAction{
+		Turn:          turnNumber,
+		Actor:         actor,
+		Action:        "status",
+		MoveName:      pokemon.Status,
+		Effectiveness: 1,
+		Timestamp:     timestamp,
+	}
+
+	switch pokemon.Status {
+	case StatusSleep:
+		if pokemon.SleepTurns > 0 {
+			pokemon.SleepTurns--
+			action.Message = fmt.Sprintf("%s is fast asleep.", pokemon.Name)
+			return action, false
+		}
+		pokemon.Status = ""
+		action.Message = fmt.Sprintf("%s woke up!", pokemon.Name)
+		return action, true
+	case StatusFreeze:
+		if rng.Float64() < ThawChance {
+			pokemon.Status = ""
+			action.Message = fmt.Sprintf("%s thawed out!", pokemon.Name)
+			return action, true
+		}
+		action.Message = fmt.Sprintf("%s is frozen solid!", pokemon.Name)
+		return action, false
+	case StatusParalysis:
+		if rng.Float64() < FullParalysisChance {
+			action.Message = fmt.Sprintf("%s is paralyzed! It can't move!", pokemon.Name)
+			return action, false
+		}
+	}
+
+	return nil, true
+}
+
+// applyResidualDamage deals end-of-turn burn or poison damage
+func applyResidualDamage(pokemon *BattlePokemon, actor string, turnNumber int, timestamp string) *TurnAction {
+	if pokemon.CurrentHP <= 0 {
+		return nil
+	}
+
+	var fraction int
+	var message string
+	switch pokemon.Status {
+	case StatusBurn:
+		fraction = BurnDamageFraction
+		message = "%s was hurt by its burn! It lost %d HP!"
+	case StatusPoison:
+		fraction = PoisonDamageFraction
+		message = "%s was hurt by poison! It lost %d HP!"
+	default:
+		return nil
+	}
+
+	damage := pokemon.MaxHP / fraction
+	if damage < 1 {
+		damage = 1
+	}
+	if damage > pokemon.CurrentHP {
+		damage = pokemon.CurrentHP
+	}
+	pokemon.CurrentHP -= damage
+
+	return &TurnAction{
+		Turn:          turnNumber,
+		Actor:         actor,
+		Action:        "status",
+		MoveName:      pokemon.Status,
+		Damage:        damage,
+		Effectiveness: 1,
+		Message:       fmt.Sprintf(message, pokemon.Name, damage),
+		Timestamp:     timestamp,
+	}
+}
+
+// effectiveSpeed returns the Pokemon's speed after stat stages and status penalties
+func effectiveSpeed(pokemon *BattlePokemon) float64 {
+	speed := float64(pokemon.Stats.Speed) * statStageMultiplier(pokemon.StatStages.Speed)
+	if pokemon.Status == StatusParalysis {
+		speed *= ParalysisSpeedModifier
+	}
+	return speed
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import "testing"
+
+func TestInflictStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		types      []string
+		existing   string
+		status     string
+		wantResult bool
+	}{
+		{name: "burn normal type", types: []string{"normal"}, status: StatusBurn, wantResult: true},
+		{name: "fire type immune to burn", types: []string{"fire"}, status: StatusBurn, wantResult: false},
+		{name: "steel type immune to poison", types: []string{"steel", "flying"}, status: StatusPoison, wantResult: false},
+		{name: "electric type immune to paralysis", types: []string{"electric"}, status: StatusParalysis, wantResult: false},
+		{name: "ice type immune to freeze", types: []string{"ice"}, status: StatusFreeze, wantResult: false},
+		{name: "already statused", types: []string{"normal"}, existing: StatusPoison, status: StatusBurn, wantResult: false},
+		{name: "unsupported ailment", types: []string{"normal"}, status: "confusion", wantResult: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pokemon := &BattlePokemon{Name: "test", CurrentHP: 100, MaxHP: 100, Types: tt.types, Status: tt.existing}
+			result := inflictStatus(newBattleRNG(1).Rand, pokemon, tt.status)
+			if result != tt.wantResult {
+				t.Errorf("inflictStatus() = %v, want %v", result, tt.wantResult)
+			}
+			if result && pokemon.Status != tt.status {
+				t.Errorf("pokemon.Status = %q, want %q", pokemon.Status, tt.status)
+			}
+		})
+	}
+}
+
+func TestInflictSleepSetsCounter(t *testing.T) {
+	pokemon := &BattlePokemon{Name: "test", CurrentHP: 100, MaxHP: 100, Types: []string{"normal"}}
+	if !inflictStatus(newBattleRNG(1).Rand, pokemon, StatusSleep) {
+		t.Fatal("inflictStatus() = false, want true")
+	}
+	if pokemon.SleepTurns < MinSleepTurns || pokemon.SleepTurns > MaxSleepTurns {
+		t.Errorf("SleepTurns = %d, want between %d and %d", pokemon.SleepTurns, MinSleepTurns, MaxSleepTurns)
+	}
+}
+
+func TestApplyResidualDamage(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     string
+		currentHP  int
+		wantDamage int
+	}{
+		{name: "burn", status: StatusBurn, currentHP: 160, wantDamage: 10},
+		{name: "poison", status: StatusPoison, currentHP: 160, wantDamage: 20},
+		{name: "capped at remaining hp", status: StatusPoison, currentHP: 5, wantDamage: 5},
+		{name: "paralysis has no residual damage", status: StatusParalysis, currentHP: 160, wantDamage: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pokemon := &BattlePokemon{Name: "test", CurrentHP: tt.currentHP, MaxHP: 160, Status: tt.status}
+			action := applyResidualDamage(pokemon, "player", 1, "")
+			damage := 0
+			if action != nil {
+				damage = action.Damage
+			}
+			if damage != tt.wantDamage {
+				t.Errorf("applyResidualDamage() damage = %d, want %d", damage, tt.wantDamage)
+			}
+			if pokemon.CurrentHP != tt.currentHP-tt.wantDamage {
+				t.Errorf("CurrentHP = %d, want %d", pokemon.CurrentHP, tt.currentHP-tt.wantDamage)
+			}
+		})
+	}
+}
+
+func TestCheckStatusBeforeMoveSleep(t *testing.T) {
+	pokemon := &BattlePokemon{Name: "test", Status: StatusSleep, SleepTurns: 2}
+
+	for i := 0; i < 2; i++ {
+		if _, canMove := checkStatusBeforeMove(newBattleRNG(1).Rand, pokemon, "player", i+1, ""); canMove {
+			t.Fatalf("turn %d: canMove = true while asleep", i+1)
+		}
+	}
+
+	action, canMove := checkStatusBeforeMove(newBattleRNG(1).Rand, pokemon, "player", 3, "")
+	if !canMove || action == nil {
+		t.Fatalf("expected Pokemon to wake up and move, got canMove = %v", canMove)
+	}
+	if pokemon.Status != "" {
+		t.Errorf("Status = %q after waking, want empty", pokemon.Status)
+	}
+}
+
+func TestEffectiveSpeedParalysis(t *testing.T) {
+	pokemon := &BattlePokemon{Stats: PokemonStats{Speed: 100}, Status: StatusParalysis}
+	if speed := effectiveSpeed(pokemon); speed != 50 {
+		t.Errorf("effectiveSpeed() = %v, want 50", speed)
+	}
+}
+
+func TestStatusMoveTypeImmunity(t *testing.T) {
+	thunderWave := PokemonMove{Name: "thunder-wave", Type: "electric", CurrentPP: 20, DamageClass: "status", Ailment: StatusParalysis}
+	toxic := PokemonMove{Name: "toxic", Type: "poison", CurrentPP: 10, DamageClass: "status", Ailment: StatusPoison}
+
+	tests := []struct {
+		name       string
+		move       PokemonMove
+		types      []string
+		wantStatus string
+	}{
+		{name: "thunder wave paralyzes normal types", move: thunderWave, types: []string{"normal"}, wantStatus: StatusParalysis},
+		{name: "thunder wave doesn't affect ground types", move: thunderWave, types: []string{"ground", "rock"}},
+		{name: "toxic poisons normal types", move: toxic, types: []string{"normal"}, wantStatus: StatusPoison},
+		{name: "toxic doesn't affect steel types", move: toxic, types: []string{"steel"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attacker := &BattlePokemon{Name: "attacker", CurrentHP: 100, MaxHP: 100, Types: []string{"normal"}}
+			defender := &BattlePokemon{Name: "defender", CurrentHP: 100, MaxHP: 100, Types: tt.types}
+
+			action := executeMove(newBattleRNG(1).Rand, nil, attacker, defender, &tt.move, "player", 1, "")
+			if defender.Status != tt.wantStatus || action.StatusInflicted != tt.wantStatus {
+				t.Errorf("status = %q, inflicted %q, want %q", defender.Status, action.StatusInflicted, tt.wantStatus)
+			}
+			if tt.wantStatus == "" && action.Effectiveness != 0 {
+				t.Errorf("effectiveness = %v, want 0 for an immune target", action.Effectiveness)
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"backend/config"
+)
+
+// ErrBattleNotFound is returned when a battle doesn't exist or has expired
+var ErrBattleNotFound = errors.New("battle not found")
+
+// ErrBattleConflict is returned when a battle was saved by another request since it was loaded
+var ErrBattleConflict = errors.New("battle was modified concurrently")
+
+const (
+	RecordIdSuffixBytes = 4 // Random bytes after the user and timestamp in new battle and tournament IDs
+	MaxCreateAttempts   = 3 // IDs a create tries before giving up on conflicts
+)
+
+// BattleStore persists battle state between requests. Save only succeeds if the
+// stored battle still has the version that was loaded, and increments it.
+type BattleStore interface {
+	Save(battle *BattleState) error
+	Load(battleId string) (*BattleState, error)
+}
+
+var (
+	battleStore     BattleStore
+	battleStoreOnce sync.Once
+)
+
+// getBattleStore returns the configured battle store, creating it on first use.
+// If DynamoDB is configured but can't be initialized the server stops, rather than
+// quietly keeping battles in memory where other instances can't see them.
+func getBattleStore() BattleStore {
+	battleStoreOnce.Do(func() {
+		storageConfig := config.LoadBattleStorageConfig()
+
+		if storageConfig.Backend == "dynamodb" {
+			store, err := newDynamoBattleStore(storageConfig.TableName, storageConfig.TTL)
+			if err == nil {
+				log.Printf("Using DynamoDB battle storage: %s", storageConfig.TableName)
+				battleStore = store
+				return
+			}
+			log.Fatalf("Error creating DynamoDB battle store: %v", err)
+		}
+		if storageConfig.Backend != "memory" {
+			log.Fatalf("Unknown BATTLE_STORAGE %q, expected memory or dynamodb", storageConfig.Backend)
+		}
+
+		log.Printf("Using in-memory battle storage")
+		battleStore = newMemoryBattleStore(storageConfig.TTL)
+	})
+	return battleStore
+}
+
+// InitStorage creates every battle store up front, so a misconfigured DynamoDB
+// backend stops the server at startup instead of on the first request
+func InitStorage() {
+	getBattleStore()
+	getBattleHistoryStore()
+	getRatingStore()
+	getTournamentStore()
+}
+
+// newRecordId returns an ID for a new battle or tournament. The random suffix keeps
+// two creates by the same user in the same second from colliding.
+func newRecordId(userId string, now time.Time) (string, error) {
+	suffix := make([]byte, RecordIdSuffixBytes)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return fmt.Sprintf("%s_%d_%s", userId, now.Unix(), hex.EncodeToString(suffix)), nil
+}
+
+// createBattle saves a new battle under a fresh ID, picking another if the ID is
+// already taken
+func createBattle(battle *BattleState, userId string, now time.Time) error {
+	for attempt := 1; ; attempt++ {
+		battleId, err := newRecordId(userId, now)
+		if err != nil {
+			return err
+		}
+		battle.BattleId = battleId
+
+		err = saveBattleState(battle)
+		if !errors.Is(err, ErrBattleConflict) || attempt == MaxCreateAttempts {
+			return err
+		}
+	}
+}
+
+// memoryBattleStore keeps battles in memory. Battles are lost on restart.
+// Battles are stored encoded so callers never share state between requests.
+type memoryBattleStore struct {
+	battles map[string]memoryBattleEntry
+	mutex   *sync.RWMutex
+	ttl     time.Duration
+}
+
+type memoryBattleEntry struct {
+	data      []byte
+	version   int
+	updatedAt string
+}
+
+// newMemoryBattleStore creates an in-memory store and starts its cleanup routine
+func newMemoryBattleStore(ttl time.Duration) *memoryBattleStore {
+	store := &memoryBattleStore{
+		battles: make(map[string]memoryBattleEntry),
+		mutex:   &sync.RWMutex{},
+		ttl:     ttl,
+	}
+
+	go func() {
+		ticker := time.NewTicker(30 * time.Minute) // Clean up every 30 minutes
+		defer ticker.Stop()
+
+		for range ticker.C {
+			store.cleanupOldBattles()
+		}
+	}()
+
+	return store
+}
+
+func (s *memoryBattleStore) Save(battle *BattleState) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if existing, exists := s.battles[battle.BattleId]; exists && existing.version != battle.Version {
+		return ErrBattleConflict
+	}
+
+	battle.Version++
+	data, err := json.Marshal(battle)
+	if err != nil {
+		battle.Version--
+		return fmt.Errorf("failed to encode battle: %w", err)
+	}
+
+	// Store battle in memory using battleId as key
+	s.battles[battle.BattleId] = memoryBattleEntry{
+		data:      data,
+		version:   battle.Version,
+		updatedAt: battle.UpdatedAt,
+	}
+	return nil
+}
+
+func (s *memoryBattleStore) Load(battleId string) (*BattleState, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entry, exists := s.battles[battleId]
+	if !exists {
+		return nil, ErrBattleNotFound
+	}
+
+	var battle BattleState
+	if err := json.Unmarshal(entry.data, &battle); err != nil {
+		return nil, fmt.Errorf("failed to decode battle: %w", err)
+	}
+	return &battle, nil
+}
+
+// cleanupOldBattles removes battles that haven't been updated within the TTL
+func (s *memoryBattleStore) cleanupOldBattles() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	cutoff := time.Now().Add(-s.ttl)
+
+	for battleId, entry := range s.battles {
+		updatedAt, err := time.Parse(time.RFC3339, entry.updatedAt)
+		if err != nil {
+			log.Printf("Error parsing battle update time: %v", err)
+			continue
+		}
+
+		if updatedAt.Before(cutoff) {
+			delete(s.battles, battleId)
+			log.Printf("Cleaned up old battle: %s", battleId)
+		}
+	}
+}
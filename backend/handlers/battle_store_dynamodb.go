@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// dynamoBattleStore persists battles in DynamoDB so they survive restarts and
+// can be shared between instances. Expired battles are removed by DynamoDB TTL.
+type dynamoBattleStore struct {
+	client    *dynamodb.Client
+	tableName string
+	ttl       time.Duration
+}
+
+// battleItem is the DynamoDB representation of a battle
+type battleItem struct {
+	BattleId  string `dynamodbav:"battleId"`
+	UserId    string `dynamodbav:"userId"`
+	Data      string `dynamodbav:"data"`      // JSON encoded BattleState
+	Version   int    `dynamodbav:"version"`   // Matches BattleState.Version, used for conditional writes
+	ExpiresAt int64  `dynamodbav:"expiresAt"` // TTL attribute, Unix seconds
+}
+
+func newDynamoBattleStore(tableName string, ttl time.Duration) (*dynamoBattleStore, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &dynamoBattleStore{
+		client:    dynamodb.NewFromConfig(cfg),
+		tableName: tableName,
+		ttl:       ttl,
+	}, nil
+}
+
+func (s *dynamoBattleStore) Save(battle *BattleState) error {
+	loadedVersion := battle.Version
+	battle.Version++
+
+	data, err := json.Marshal(battle)
+	if err != nil {
+		battle.Version = loadedVersion
+		return fmt.Errorf("failed to encode battle: %w", err)
+	}
+
+	item, err := attributevalue.MarshalMap(battleItem{
+		BattleId:  battle.BattleId,
+		UserId:    battle.UserId,
+		Data:      string(data),
+		Version:   battle.Version,
+		ExpiresAt: time.Now().Add(s.ttl).Unix(),
+	})
+	if err != nil {
+		battle.Version = loadedVersion
+		return fmt.Errorf("failed to marshal battle item: %w", err)
+	}
+
+	// Only overwrite the version this request loaded, so concurrent moves can't clobber each other
+	_, err = s.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           &s.tableName,
+		Item:                item,
+		ConditionExpression: stringPtr("attribute_not_exists(battleId) OR version = :version"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: strconv.Itoa(loadedVersion)},
+		},
+	})
+	if err != nil {
+		battle.Version = loadedVersion
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return ErrBattleConflict
+		}
+		return fmt.Errorf("failed to save battle: %w", err)
+	}
+	return nil
+}
+
+func (s *dynamoBattleStore) Load(battleId string) (*BattleState, error) {
+	result, err := s.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: &s.tableName,
+		Key: map[string]types.AttributeValue{
+			"battleId": &types.AttributeValueMemberS{Value: battleId},
+		},
+		ConsistentRead: boolPtr(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load battle: %w", err)
+	}
+	if result.Item == nil {
+		return nil, ErrBattleNotFound
+	}
+
+	var item battleItem
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal battle item: %w", err)
+	}
+
+	// DynamoDB deletes expired items lazily, so check the TTL ourselves
+	if item.ExpiresAt < time.Now().Unix() {
+		return nil, ErrBattleNotFound
+	}
+
+	var battle BattleState
+	if err := json.Unmarshal([]byte(item.Data), &battle); err != nil {
+		return nil, fmt.Errorf("failed to decode battle: %w", err)
+	}
+	return &battle, nil
+}
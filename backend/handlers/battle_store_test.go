@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryBattleStore(t *testing.T) {
+	store := newMemoryBattleStore(time.Hour)
+
+	if _, err := store.Load("missing"); !errors.Is(err, ErrBattleNotFound) {
+		t.Errorf("Load() error = %v, want ErrBattleNotFound", err)
+	}
+
+	now := time.Now()
+	fresh := &BattleState{BattleId: "fresh", UpdatedAt: now.Format(time.RFC3339)}
+	stale := &BattleState{BattleId: "stale", UpdatedAt: now.Add(-2 * time.Hour).Format(time.RFC3339)}
+	for _, battle := range []*BattleState{fresh, stale} {
+		if err := store.Save(battle); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	store.cleanupOldBattles()
+
+	if _, err := store.Load("fresh"); err != nil {
+		t.Errorf("Load(fresh) error = %v", err)
+	}
+	if _, err := store.Load("stale"); !errors.Is(err, ErrBattleNotFound) {
+		t.Errorf("Load(stale) error = %v, want ErrBattleNotFound", err)
+	}
+}
+
+func TestMemoryBattleStoreVersionConflict(t *testing.T) {
+	store := newMemoryBattleStore(time.Hour)
+	if err := store.Save(&BattleState{BattleId: "battle", UpdatedAt: time.Now().Format(time.RFC3339)}); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	first, _ := store.Load("battle")
+	second, _ := store.Load("battle")
+
+	first.CurrentTurn = "finished"
+	if err := store.Save(first); err != nil {
+		t.Fatalf("Save(first) error = %v", err)
+	}
+	if err := store.Save(second); !errors.Is(err, ErrBattleConflict) {
+		t.Errorf("Save(second) error = %v, want ErrBattleConflict", err)
+	}
+
+	loaded, _ := store.Load("battle")
+	if loaded.CurrentTurn != "finished" || loaded.Version != 2 {
+		t.Errorf("loaded CurrentTurn = %q, Version = %d, want finished and 2", loaded.CurrentTurn, loaded.Version)
+	}
+}
+
+func TestCreateBattleSameSecond(t *testing.T) {
+	now := time.Now()
+	first := &BattleState{UserId: "same-second", UpdatedAt: now.Format(time.RFC3339)}
+	second := &BattleState{UserId: "same-second", UpdatedAt: now.Format(time.RFC3339)}
+	for _, battle := range []*BattleState{first, second} {
+		if err := createBattle(battle, "same-second", now); err != nil {
+			t.Fatalf("createBattle() error = %v", err)
+		}
+	}
+
+	if first.BattleId == second.BattleId {
+		t.Fatalf("both battles got ID %s", first.BattleId)
+	}
+	for _, battle := range []*BattleState{first, second} {
+		if _, err := getBattleStore().Load(battle.BattleId); err != nil {
+			t.Errorf("Load(%s) error = %v", battle.BattleId, err)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+)
+
+const MaxTeamSize = 6
+
+// activePokemon returns the Pokemon currently in battle for the given side
+func (b *BattleState) activePokemon(actor string) *BattlePokemon {
+	if actor == "player" {
+		return &b.PlayerTeam[b.PlayerActive]
+	}
+	return &b.ComputerTeam[b.ComputerActive]
+}
+
+// team returns the given side's team
+func (b *BattleState) team(actor string) []BattlePokemon {
+	if actor == "player" {
+		return b.PlayerTeam
+	}
+	return b.ComputerTeam
+}
+
+// activeIndex returns the team index of the given side's Pokemon in battle
+func (b *BattleState) activeIndex(actor string) int {
+	if actor == "player" {
+		return b.PlayerActive
+	}
+	return b.ComputerActive
+}
+
+// opponentOf returns the side facing the given actor
+func opponentOf(actor string) string {
+	if actor == "player" {
+		return "computer"
+	}
+	return "player"
+}
+
+// validateTeamRequest checks the team size, Pokemon IDs and movesets a user asked for
+func validateTeamRequest(pokemonIds []int, movesets [][]string) error {
+	if len(pokemonIds) > MaxTeamSize {
+		return fmt.Errorf("teams can have at most %d Pokemon", MaxTeamSize)
+	}
+	if len(movesets) > len(pokemonIds) {
+		return fmt.Errorf("more movesets than team members")
+	}
+	for _, pokemonId := range pokemonIds {
+		if pokemonId < 1 || pokemonId > 1000 {
+			return fmt.Errorf("Pokemon IDs must be between 1 and 1000")
+		}
+	}
+	return nil
+}
+
+// fetchBattleTeam fetches battle data for every Pokemon in a team and applies its spread.
+// movesets, abilities and items may be shorter than the team; members without one get
+// the default moveset, their first regular ability and no item.
+func fetchBattleTeam(pokemonIds []int, spreads []PokemonSpread, movesets [][]string, abilities, items []string) ([]BattlePokemon, error) {
+	team := make([]BattlePokemon, 0, len(pokemonIds))
+	for i, pokemonId := range pokemonIds {
+		var moveNames []string
+		if i < len(movesets) {
+			moveNames = movesets[i]
+		}
+		var ability string
+		if i < len(abilities) {
+			ability = abilities[i]
+		}
+
+		pokemon, err := fetchBattlePokemonData(pokemonId, spreads[i].Level, moveNames, ability)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch Pokemon %d: %w", pokemonId, err)
+		}
+		applySpread(pokemon, spreads[i])
+		if i < len(items) {
+			pokemon.Item = items[i]
+		}
+		team = append(team, *pokemon)
+	}
+	return team, nil
+}
+
+// cloneTeam copies a team so later changes to HP and PP don't affect the copy
+func cloneTeam(team []BattlePokemon) []BattlePokemon {
+	clone := make([]BattlePokemon, len(team))
+	for i, pokemon := range team {
+		clone[i] = pokemon
+		clone[i].Moves = append([]PokemonMove(nil), pokemon.Moves...)
+		clone[i].Types = append([]string(nil), pokemon.Types...)
+	}
+	return clone
+}
+
+// teamNames joins the names of a team for logging
+func teamNames(team []BattlePokemon) string {
+	names := make([]string, len(team))
+	for i, pokemon := range team {
+		names[i] = pokemon.Name
+	}
+	return strings.Join(names, ", ")
+}
+
+// teamDefeated reports whether every Pokemon in a team has fainted
+func teamDefeated(team []BattlePokemon) bool {
+	for _, pokemon := range team {
+		if pokemon.CurrentHP > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// nextAvailablePokemon returns the index of the first Pokemon that can still battle, or -1
+func nextAvailablePokemon(team []BattlePokemon) int {
+	for i, pokemon := range team {
+		if pokemon.CurrentHP > 0 {
+			return i
+		}
+	}
+	return -1
+}
+
+// validateSwitch checks that the team member at index can be sent out
+func validateSwitch(team []BattlePokemon, active int, index int) error {
+	if index < 0 || index >= len(team) {
+		return fmt.Errorf("invalid team member %d", index)
+	}
+	if index == active {
+		return fmt.Errorf("%s is already in battle", team[index].Name)
+	}
+	if team[index].CurrentHP <= 0 {
+		return fmt.Errorf("%s has fainted and can't battle", team[index].Name)
+	}
+	return nil
+}
+
+// switchPokemon sends out a different team member. Stat stages are reset on the
+// Pokemon leaving the field, while status conditions persist.
+func switchPokemon(battle *BattleState, actor string, index int, turnNumber int, timestamp string) *TurnAction {
+	outgoing := battle.activePokemon(actor)
+	outgoing.StatStages = StatStages{}
+
+	if actor == "player" {
+		battle.PlayerActive = index
+	} else {
+		battle.ComputerActive = index
+	}
+	incoming := battle.activePokemon(actor)
+
+	trainer := battle.trainerName(actor)
+	message := fmt.Sprintf("%s sent out %s!", trainer, incoming.Name)
+	if outgoing.CurrentHP > 0 {
+		message = fmt.Sprintf("%s withdrew %s and sent out %s!", trainer, outgoing.Name, incoming.Name)
+	}
+
+	for _, effect := range switchInEffects(battle, actor) {
+		message += " " + effect
+	}
+
+	return &TurnAction{
+		Turn:          turnNumber,
+		Actor:         actor,
+		Action:        "switch",
+		MoveName:      incoming.Name,
+		Effectiveness: 1,
+		Message:       message,
+		Timestamp:     timestamp,
+	}
+}
+
+// faintAction logs a Pokemon fainting
+func faintAction(pokemon *BattlePokemon, actor string, turnNumber int, timestamp string) *TurnAction {
+	return &TurnAction{
+		Turn:          turnNumber,
+		Actor:         actor,
+		Action:        "faint",
+		MoveName:      pokemon.Name,
+		Effectiveness: 1,
+		Message:       fmt.Sprintf("%s fainted!", pokemon.Name),
+		Timestamp:     timestamp,
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import "testing"
+
+func testTeam() []BattlePokemon {
+	return []BattlePokemon{
+		{Name: "bulbasaur", CurrentHP: 0, MaxHP: 45},
+		{Name: "charmander", CurrentHP: 39, MaxHP: 39},
+		{Name: "squirtle", CurrentHP: 44, MaxHP: 44},
+	}
+}
+
+func TestValidateSwitch(t *testing.T) {
+	tests := []struct {
+		name    string
+		index   int
+		wantErr bool
+	}{
+		{name: "healthy bench member", index: 2, wantErr: false},
+		{name: "already active", index: 1, wantErr: true},
+		{name: "fainted", index: 0, wantErr: true},
+		{name: "out of range", index: 3, wantErr: true},
+		{name: "negative index", index: -1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSwitch(testTeam(), 1, tt.index)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("validateSwitch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTeamDefeated(t *testing.T) {
+	team := testTeam()
+	if teamDefeated(team) {
+		t.Error("teamDefeated() = true with healthy Pokemon left")
+	}
+	if next := nextAvailablePokemon(team); next != 1 {
+		t.Errorf("nextAvailablePokemon() = %d, want 1", next)
+	}
+
+	for i := range team {
+		team[i].CurrentHP = 0
+	}
+	if !teamDefeated(team) {
+		t.Error("teamDefeated() = false with every Pokemon fainted")
+	}
+	if next := nextAvailablePokemon(team); next != -1 {
+		t.Errorf("nextAvailablePokemon() = %d, want -1", next)
+	}
+}
+
+func TestSwitchPokemonResetsStatStages(t *testing.T) {
+	battle := &BattleState{PlayerTeam: testTeam(), PlayerActive: 1}
+	battle.PlayerTeam[1].StatStages.Attack = 2
+	battle.PlayerTeam[1].Status = StatusBurn
+
+	action := switchPokemon(battle, "player", 2, 1, "")
+
+	if battle.PlayerActive != 2 {
+		t.Errorf("PlayerActive = %d, want 2", battle.PlayerActive)
+	}
+	if battle.PlayerTeam[1].StatStages.Attack != 0 {
+		t.Errorf("outgoing Attack stage = %d, want 0", battle.PlayerTeam[1].StatStages.Attack)
+	}
+	if battle.PlayerTeam[1].Status != StatusBurn {
+		t.Errorf("outgoing Status = %q, want burn to persist", battle.PlayerTeam[1].Status)
+	}
+	if action.Action != "switch" {
+		t.Errorf("action.Action = %q, want switch", action.Action)
+	}
+}
+
+func TestProcessBattleTurnForcedSwitch(t *testing.T) {
+	battle := &BattleState{
+		PlayerTeam:     testTeam(),
+		PlayerActive:   0,
+		ComputerTeam:   []BattlePokemon{{Name: "pidgey", CurrentHP: 40, MaxHP: 40}},
+		CurrentTurn:    "switch",
+		BattleStatus:   "active",
+		ForcedSwitches: []string{"player"},
+	}
+
+	if _, err := processBattleTurn(battle, BattleChoice{Action: "attack", MoveName: "tackle"}); err == nil {
+		t.Fatal("expected an error when attacking during a forced switch")
+	}
+
+	result, err := processBattleTurn(battle, BattleChoice{Action: "switch", SwitchTo: 2})
+	if err != nil {
+		t.Fatalf("processBattleTurn() error = %v", err)
+	}
+	if battle.PlayerActive != 2 || battle.CurrentTurn != "player" || len(battle.ForcedSwitches) != 0 {
+		t.Errorf("PlayerActive = %d, CurrentTurn = %q, want 2 and player", battle.PlayerActive, battle.CurrentTurn)
+	}
+	if result.PlayerAction == nil || result.PlayerAction.Action != "switch" {
+		t.Errorf("PlayerAction = %+v, want a switch", result.PlayerAction)
+	}
+	if len(battle.TurnHistory) != 1 {
+		t.Errorf("TurnHistory has %d entries, want the switch recorded", len(battle.TurnHistory))
+	}
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import "testing"
+
+func TestHasType(t *testing.T) {
+	pokemon := &BattlePokemon{Types: []string{"grass", "poison"}}
+
+	tests := []struct {
+		name        string
+		pokemonType string
+		want        bool
+	}{
+		{name: "primary type", pokemonType: "grass", want: true},
+		{name: "secondary type", pokemonType: "poison", want: true},
+		{name: "case insensitive", pokemonType: "Grass", want: true},
+		{name: "missing type", pokemonType: "fire", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := hasType(pokemon, tt.pokemonType)
+			if result != tt.want {
+				t.Errorf("hasType() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestMoveHits(t *testing.T) {
+	tests := []struct {
+		name     string
+		accuracy int
+	}{
+		{name: "never misses", accuracy: 0},
+		{name: "perfect accuracy", accuracy: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			move := &PokemonMove{Name: "test", Accuracy: tt.accuracy}
+			pokemon := &BattlePokemon{}
+			for i := 0; i < 100; i++ {
+				if !moveHits(newBattleRNG(1).Rand, pokemon, pokemon, move) {
+					t.Fatalf("moveHits() = false for accuracy %d", tt.accuracy)
+				}
+			}
+		})
+	}
+}
+
+func TestDamageStats(t *testing.T) {
+	attacker := &BattlePokemon{Stats: PokemonStats{Attack: 50, SpecialAttack: 135}}
+	defender := &BattlePokemon{Stats: PokemonStats{Defense: 45, SpecialDefense: 95}}
+
+	tests := []struct {
+		name        string
+		damageClass string
+		wantAttack  int
+		wantDefense int
+	}{
+		{name: "physical", damageClass: "physical", wantAttack: 50, wantDefense: 45},
+		{name: "special", damageClass: "special", wantAttack: 135, wantDefense: 95},
+		{name: "unset defaults to physical", damageClass: "", wantAttack: 50, wantDefense: 45},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attack, defense := damageStats(attacker, defender, &PokemonMove{DamageClass: tt.damageClass}, false)
+			if attack != tt.wantAttack || defense != tt.wantDefense {
+				t.Errorf("damageStats() = (%d, %d), want (%d, %d)", attack, defense, tt.wantAttack, tt.wantDefense)
+			}
+		})
+	}
+}
+
+func TestSimultaneousKnockout(t *testing.T) {
+	doubleEdge := PokemonMove{Name: "double-edge", Power: 120, Type: "normal", CurrentPP: 15, DamageClass: "physical", Drain: -33}
+	splash := PokemonMove{Name: "splash", Type: "normal", CurrentPP: 40, DamageClass: "status"}
+
+	tests := []struct {
+		name       string
+		faster     string
+		moves      map[string]PokemonMove
+		burned     bool
+		wantWinner string
+	}{
+		{"player's recoil knocks out both", "player", map[string]PokemonMove{"player": doubleEdge, "computer": splash}, false, "player"},
+		{"computer's recoil knocks out both", "computer", map[string]PokemonMove{"player": splash, "computer": doubleEdge}, false, "computer"},
+		{"faster side's burn ticks first", "player", map[string]PokemonMove{"player": splash, "computer": splash}, true, "computer"},
+		{"slower side's burn ticks second", "computer", map[string]PokemonMove{"player": splash, "computer": splash}, true, "player"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			battle := testAIBattle()
+			battle.RNG = newBattleRNG(1)
+			battle.CurrentTurn = "player"
+			battle.BattleStatus = "active"
+			choices := make(map[string]BattleChoice)
+			for _, actor := range []string{"player", "computer"} {
+				pokemon := battle.activePokemon(actor)
+				pokemon.CurrentHP = 1
+				pokemon.Moves = []PokemonMove{tt.moves[actor]}
+				if actor == tt.faster {
+					pokemon.Stats.Speed = 200
+				}
+				if tt.burned {
+					pokemon.Status = "burn"
+				}
+				choices[actor] = BattleChoice{Action: "attack", MoveName: tt.moves[actor].Name}
+			}
+
+			turnResult := resolveTurn(battle, choices)
+			if !turnResult.BattleEnded || turnResult.Winner != tt.wantWinner || battle.winningSide() != tt.wantWinner {
+				t.Errorf("ended = %v, winner = %q, winning side = %q, want %q", turnResult.BattleEnded, turnResult.Winner, battle.winningSide(), tt.wantWinner)
+			}
+		})
+	}
+}
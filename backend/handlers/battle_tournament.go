@@ -0,0 +1,851 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/bits"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"backend/config"
+	"backend/middleware"
+)
+
+const (
+	TournamentSingleElimination = "single-elimination"
+	TournamentRoundRobin        = "round-robin"
+	DefaultTournamentSize       = 16
+	MaxTournamentSize           = 16         // A full round robin of the largest possible teams is about 270KB
+	MaxTournamentItemBytes      = 400 * 1024 // DynamoDB's item size limit, which a whole tournament has to fit in
+	MaxTournamentUpdateAttempts = 3          // Retries when finished battles advance the same tournament at once
+)
+
+// ErrTournamentNotFound is returned when a tournament doesn't exist
+var ErrTournamentNotFound = errors.New("tournament not found")
+
+// ErrTournamentConflict is returned when a tournament was saved by another request since it was loaded
+var ErrTournamentConflict = errors.New("tournament was modified concurrently")
+
+// TournamentEntrant is a registered user and the team they play every match with
+type TournamentEntrant struct {
+	UserId       string          `json:"userId"`
+	Username     string          `json:"username"`
+	Team         []BattlePokemon `json:"team"`
+	RegisteredAt string          `json:"registeredAt"`
+}
+
+// TournamentMatch is one pairing in a round. A match without an opponent is a bye,
+// which the player wins without playing.
+type TournamentMatch struct {
+	Round          int    `json:"round"`
+	PlayerUserId   string `json:"playerUserId"`
+	OpponentUserId string `json:"opponentUserId,omitempty"`
+	BattleId       string `json:"battleId,omitempty"`
+	WinnerUserId   string `json:"winnerUserId,omitempty"`
+	Status         string `json:"status"`              // "scheduled", "active", "finished" or "bye"
+	EndReason      string `json:"endReason,omitempty"` // "expired" if the battle expired unfinished and the player was given the win
+}
+
+// TournamentStanding is an entrant's record so far
+type TournamentStanding struct {
+	UserId     string `json:"userId"`
+	Username   string `json:"username"`
+	Wins       int    `json:"wins"`
+	Losses     int    `json:"losses"`
+	Byes       int    `json:"byes"`
+	Eliminated bool   `json:"eliminated"` // Single elimination only
+}
+
+type Tournament struct {
+	TournamentId string              `json:"tournamentId"`
+	Name         string              `json:"name"`
+	Format       string              `json:"format"` // "single-elimination" or "round-robin"
+	OwnerUserId  string              `json:"ownerUserId"`
+	OwnerName    string              `json:"ownerName"`
+	Status       string              `json:"status"` // "registration", "active" or "finished"
+	MaxEntrants  int                 `json:"maxEntrants"`
+	Entrants     []TournamentEntrant `json:"entrants"`
+	Matches      []TournamentMatch   `json:"matches"`      // In round order
+	CurrentRound int                 `json:"currentRound"` // 0 until the tournament starts
+	Rounds       int                 `json:"rounds"`       // Total rounds, known once the tournament starts
+	WinnerUserId string              `json:"winnerUserId,omitempty"`
+	Version      int                 `json:"version"` // Incremented on every save
+	CreatedAt    string              `json:"createdAt"`
+	UpdatedAt    string              `json:"updatedAt"`
+}
+
+// TournamentStore persists tournaments. Like BattleStore, Save only succeeds if the
+// stored tournament still has the version that was loaded, and increments it.
+type TournamentStore interface {
+	Save(tournament *Tournament) error
+	Load(tournamentId string) (*Tournament, error)
+}
+
+type CreateTournamentRequest struct {
+	Name        string `json:"name"`
+	Format      string `json:"format"`                // "single-elimination" or "round-robin"
+	MaxEntrants int    `json:"maxEntrants,omitempty"` // Defaults to 16
+}
+
+type TournamentResponse struct {
+	Tournament *Tournament          `json:"tournament,omitempty"`
+	Standings  []TournamentStanding `json:"standings,omitempty"`
+	Error      string               `json:"error,omitempty"`
+}
+
+var (
+	tournamentStore     TournamentStore
+	tournamentStoreOnce sync.Once
+)
+
+// getTournamentStore returns the configured tournament store, creating it on first use.
+// Like battle storage, the server stops if DynamoDB can't be initialized.
+func getTournamentStore() TournamentStore {
+	tournamentStoreOnce.Do(func() {
+		storageConfig := config.LoadBattleStorageConfig()
+
+		if storageConfig.Backend == "dynamodb" {
+			store, err := newDynamoTournamentStore(storageConfig.TournamentTableName)
+			if err == nil {
+				tournamentStore = store
+				return
+			}
+			log.Fatalf("Error creating DynamoDB tournament store: %v", err)
+		}
+
+		tournamentStore = newMemoryTournamentStore()
+	})
+	return tournamentStore
+}
+
+// entrant returns a registered user, or nil
+func (t *Tournament) entrant(userId string) *TournamentEntrant {
+	for i := range t.Entrants {
+		if t.Entrants[i].UserId == userId {
+			return &t.Entrants[i]
+		}
+	}
+	return nil
+}
+
+// seed shuffles the entrants and generates the pairings. Single elimination only
+// pairs the first round, since later rounds depend on who wins; round robin pairs
+// every round up front with the circle method.
+func (t *Tournament) seed(rng *rand.Rand) {
+	ids := make([]string, len(t.Entrants))
+	for i, entrant := range t.Entrants {
+		ids[i] = entrant.UserId
+	}
+	rng.Shuffle(len(ids), func(i, j int) { ids[i], ids[j] = ids[j], ids[i] })
+
+	t.Matches = nil
+	switch t.Format {
+	case TournamentSingleElimination:
+		// Pad the bracket to a power of two; the top seeds get the byes
+		size := 1
+		for size < len(ids) {
+			size *= 2
+		}
+		t.Rounds = bits.Len(uint(size)) - 1
+		for k := 0; k < size/2; k++ {
+			match := TournamentMatch{Round: 1, PlayerUserId: ids[k], Status: "scheduled"}
+			if j := size - 1 - k; j < len(ids) {
+				match.OpponentUserId = ids[j]
+			}
+			t.Matches = append(t.Matches, match)
+		}
+
+	case TournamentRoundRobin:
+		// An odd field gets a placeholder, and whoever meets it has a bye that round
+		if len(ids)%2 == 1 {
+			ids = append(ids, "")
+		}
+		n := len(ids)
+		t.Rounds = n - 1
+		for round := 1; round <= t.Rounds; round++ {
+			for k := 0; k < n/2; k++ {
+				player, opponent := ids[k], ids[n-1-k]
+				if player == "" {
+					player, opponent = opponent, player
+				}
+				t.Matches = append(t.Matches, TournamentMatch{Round: round, PlayerUserId: player, OpponentUserId: opponent, Status: "scheduled"})
+			}
+			// Keep the first entrant in place and rotate the rest
+			ids = append([]string{ids[0], ids[n-1]}, ids[1:n-1]...)
+		}
+	}
+}
+
+// startRound begins a round: byes are decided immediately and every other match
+// gets a battle. Returns the battles to save.
+func (t *Tournament) startRound(round int, now time.Time) []*BattleState {
+	t.CurrentRound = round
+
+	var battles []*BattleState
+	index := 0
+	for i := range t.Matches {
+		match := &t.Matches[i]
+		if match.Round != round {
+			continue
+		}
+		if match.OpponentUserId == "" {
+			match.Status = "bye"
+			match.WinnerUserId = match.PlayerUserId
+			continue
+		}
+
+		battle := t.newMatchBattle(match, index, now)
+		match.BattleId = battle.BattleId
+		match.Status = "active"
+		battles = append(battles, battle)
+		index++
+	}
+	return battles
+}
+
+// newMatchBattle creates the PvP battle for a match. Both entrants start from their
+// registered teams at full health.
+func (t *Tournament) newMatchBattle(match *TournamentMatch, index int, now time.Time) *BattleState {
+	player := t.entrant(match.PlayerUserId)
+	opponent := t.entrant(match.OpponentUserId)
+
+	battle := &BattleState{
+		BattleId:            fmt.Sprintf("%s_r%d_m%d", t.TournamentId, match.Round, index+1),
+		UserId:              player.UserId,
+		Mode:                BattleModePvP,
+		OpponentUserId:      opponent.UserId,
+		PlayerName:          player.Username,
+		OpponentName:        opponent.Username,
+		PlayerTeam:          cloneTeam(player.Team),
+		ComputerTeam:        cloneTeam(opponent.Team),
+		CurrentTurn:         "player",
+		BattleStatus:        "active",
+		Seed:                newBattleSeed(),
+		InitialPlayerTeam:   cloneTeam(player.Team),
+		InitialComputerTeam: cloneTeam(opponent.Team),
+		TournamentId:        t.TournamentId,
+		CreatedAt:           now.Format(time.RFC3339),
+		UpdatedAt:           now.Format(time.RFC3339),
+		TurnHistory:         []TurnAction{},
+	}
+	applyLeadEffects(battle, now.Format(time.RFC3339))
+	battle.startTurnClock(now)
+	return battle
+}
+
+// recordResult marks the match played in a battle as won by winnerUserId and
+// advances the tournament if that completed the round. Returns whether the match
+// was found and the battles for any round that started.
+func (t *Tournament) recordResult(battleId, winnerUserId string, now time.Time) (bool, []*BattleState) {
+	for i := range t.Matches {
+		match := &t.Matches[i]
+		if match.BattleId != battleId || match.Status != "active" {
+			continue
+		}
+		match.Status = "finished"
+		match.WinnerUserId = winnerUserId
+		return true, t.advance(now)
+	}
+	return false, nil
+}
+
+// expireMatch records a match whose battle expired before it finished. Nobody can
+// say who would have won, so the player, the higher seed, advances.
+func (t *Tournament) expireMatch(battleId string, now time.Time) (bool, []*BattleState) {
+	for i := range t.Matches {
+		match := &t.Matches[i]
+		if match.BattleId == battleId && match.Status == "active" {
+			match.EndReason = "expired"
+			return t.recordResult(battleId, match.PlayerUserId, now)
+		}
+	}
+	return false, nil
+}
+
+// advance moves to the next round once every match in the current one has a winner,
+// or finishes the tournament after the last round
+func (t *Tournament) advance(now time.Time) []*BattleState {
+	var winners []string
+	for _, match := range t.Matches {
+		if match.Round != t.CurrentRound {
+			continue
+		}
+		if match.Status != "finished" && match.Status != "bye" {
+			return nil
+		}
+		winners = append(winners, match.WinnerUserId)
+	}
+
+	if t.CurrentRound == t.Rounds {
+		t.Status = "finished"
+		if t.Format == TournamentSingleElimination {
+			t.WinnerUserId = winners[0]
+		} else {
+			t.WinnerUserId = t.standings()[0].UserId
+		}
+		return nil
+	}
+
+	// Winners of neighbouring matches meet in the next round
+	if t.Format == TournamentSingleElimination {
+		for k := 0; k+1 < len(winners); k += 2 {
+			t.Matches = append(t.Matches, TournamentMatch{
+				Round:          t.CurrentRound + 1,
+				PlayerUserId:   winners[k],
+				OpponentUserId: winners[k+1],
+				Status:         "scheduled",
+			})
+		}
+	}
+	return t.startRound(t.CurrentRound+1, now)
+}
+
+// standings ranks entrants by wins, then fewest losses, then registration order
+func (t *Tournament) standings() []TournamentStanding {
+	standings := make([]TournamentStanding, len(t.Entrants))
+	index := make(map[string]int, len(t.Entrants))
+	for i, entrant := range t.Entrants {
+		standings[i] = TournamentStanding{UserId: entrant.UserId, Username: entrant.Username}
+		index[entrant.UserId] = i
+	}
+
+	for _, match := range t.Matches {
+		switch match.Status {
+		case "bye":
+			standings[index[match.PlayerUserId]].Byes++
+		case "finished":
+			loser := match.PlayerUserId
+			if loser == match.WinnerUserId {
+				loser = match.OpponentUserId
+			}
+			standings[index[match.WinnerUserId]].Wins++
+			standings[index[loser]].Losses++
+			if t.Format == TournamentSingleElimination {
+				standings[index[loser]].Eliminated = true
+			}
+		}
+	}
+
+	sort.SliceStable(standings, func(i, j int) bool {
+		if standings[i].Wins != standings[j].Wins {
+			return standings[i].Wins > standings[j].Wins
+		}
+		return standings[i].Losses < standings[j].Losses
+	})
+	return standings
+}
+
+// recordTournamentResult advances the tournament a finished battle belongs to and
+// saves the battles for any round that started
+func recordTournamentResult(battle *BattleState) error {
+	for attempt := 0; attempt < MaxTournamentUpdateAttempts; attempt++ {
+		tournament, err := getTournamentStore().Load(battle.TournamentId)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		found, battles := tournament.recordResult(battle.BattleId, battle.WinnerUserId, now)
+		if !found {
+			return nil
+		}
+		tournament.UpdatedAt = now.Format(time.RFC3339)
+
+		err = getTournamentStore().Save(tournament)
+		if errors.Is(err, ErrTournamentConflict) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		if tournament.Status == "finished" {
+			log.Printf("Tournament %s won by %s", tournament.TournamentId, tournament.WinnerUserId)
+		}
+		return saveTournamentBattles(battles)
+	}
+	return ErrTournamentConflict
+}
+
+// createTournament saves a new tournament under a fresh ID, picking another if the
+// ID is already taken
+func createTournament(tournament *Tournament, userId string, now time.Time) error {
+	for attempt := 1; ; attempt++ {
+		tournamentId, err := newRecordId(userId, now)
+		if err != nil {
+			return err
+		}
+		tournament.TournamentId = tournamentId
+
+		err = getTournamentStore().Save(tournament)
+		if !errors.Is(err, ErrTournamentConflict) || attempt == MaxCreateAttempts {
+			return err
+		}
+	}
+}
+
+// staleMatch is an active match whose result never reached the tournament
+type staleMatch struct {
+	battleId     string
+	winnerUserId string // Empty if the battle expired
+}
+
+// resolveStaleMatches records the results of active matches that will never report
+// them: battles that ran out their turn clock, finished battles whose result was
+// lost, and battles that expired after BATTLE_TTL. Returns the updated tournament.
+func resolveStaleMatches(tournament *Tournament) (*Tournament, error) {
+	var stale []staleMatch
+	for _, match := range tournament.Matches {
+		if match.Status != "active" {
+			continue
+		}
+		battle, err := getBattleStore().Load(match.BattleId)
+		if errors.Is(err, ErrBattleNotFound) {
+			stale = append(stale, staleMatch{battleId: match.BattleId})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if battle, err = enforceTurnTimeout(battle); err != nil {
+			return nil, err
+		}
+		if battle.finished() {
+			stale = append(stale, staleMatch{battleId: match.BattleId, winnerUserId: battle.WinnerUserId})
+		}
+	}
+	if len(stale) == 0 {
+		return tournament, nil
+	}
+
+	for attempt := 0; attempt < MaxTournamentUpdateAttempts; attempt++ {
+		latest, err := getTournamentStore().Load(tournament.TournamentId)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		changed := false
+		var battles []*BattleState
+		for _, match := range stale {
+			var found bool
+			var started []*BattleState
+			if match.winnerUserId == "" {
+				found, started = latest.expireMatch(match.battleId, now)
+			} else {
+				found, started = latest.recordResult(match.battleId, match.winnerUserId, now)
+			}
+			changed = changed || found
+			battles = append(battles, started...)
+		}
+		if !changed {
+			return latest, nil
+		}
+		latest.UpdatedAt = now.Format(time.RFC3339)
+
+		err = getTournamentStore().Save(latest)
+		if errors.Is(err, ErrTournamentConflict) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		log.Printf("Resolved %d stale matches in tournament %s", len(stale), latest.TournamentId)
+		return latest, saveTournamentBattles(battles)
+	}
+	return nil, ErrTournamentConflict
+}
+
+// saveTournamentBattles saves the battles for a round that just started
+func saveTournamentBattles(battles []*BattleState) error {
+	for _, battle := range battles {
+		if err := saveBattleState(battle); err != nil {
+			return fmt.Errorf("failed to create battle %s: %w", battle.BattleId, err)
+		}
+	}
+	return nil
+}
+
+// tournamentPath splits /tournaments/{tournamentId}[/{action}]
+func tournamentPath(path string) (string, string) {
+	tournamentId, action, _ := strings.Cut(strings.TrimPrefix(path, "/tournaments/"), "/")
+	return tournamentId, action
+}
+
+// writeTournament responds with a tournament and its standings
+func writeTournament(w http.ResponseWriter, tournament *Tournament) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TournamentResponse{Tournament: tournament, Standings: tournament.standings()})
+}
+
+// writeTournamentSaveError reports a failed tournament save
+func writeTournamentSaveError(w http.ResponseWriter, err error) {
+	log.Printf("Error saving tournament: %v", err)
+	if errors.Is(err, ErrTournamentConflict) {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Tournament was updated by another request, please try again"})
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+	json.NewEncoder(w).Encode(TournamentResponse{Error: "Failed to save tournament"})
+}
+
+// CreateTournamentHandler opens a tournament for registration
+func CreateTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Authentication required"})
+		return
+	}
+
+	var req CreateTournamentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Invalid request body"})
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Tournament name is required"})
+		return
+	}
+	if req.Format != TournamentSingleElimination && req.Format != TournamentRoundRobin {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: fmt.Sprintf("Format must be %s or %s", TournamentSingleElimination, TournamentRoundRobin)})
+		return
+	}
+	if req.MaxEntrants == 0 {
+		req.MaxEntrants = DefaultTournamentSize
+	}
+	if req.MaxEntrants < 2 || req.MaxEntrants > MaxTournamentSize {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: fmt.Sprintf("Tournaments can have between 2 and %d entrants", MaxTournamentSize)})
+		return
+	}
+
+	now := time.Now()
+	tournament := &Tournament{
+		Name:        req.Name,
+		Format:      req.Format,
+		OwnerUserId: user.Sub,
+		OwnerName:   user.Username,
+		Status:      "registration",
+		MaxEntrants: req.MaxEntrants,
+		Entrants:    []TournamentEntrant{},
+		Matches:     []TournamentMatch{},
+		CreatedAt:   now.Format(time.RFC3339),
+		UpdatedAt:   now.Format(time.RFC3339),
+	}
+
+	if err := createTournament(tournament, user.Sub, now); err != nil {
+		writeTournamentSaveError(w, err)
+		return
+	}
+
+	log.Printf("User %s created %s tournament %s", user.Username, tournament.Format, tournament.TournamentId)
+	writeTournament(w, tournament)
+}
+
+// TournamentHandler returns a tournament's bracket and standings to any signed-in user
+func TournamentHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Expected format: /tournaments/{tournamentId}
+	tournamentId, action := tournamentPath(r.URL.Path)
+	if tournamentId == "" || action != "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Tournament ID required"})
+		return
+	}
+
+	tournament, err := getTournamentStore().Load(tournamentId)
+	if err != nil {
+		log.Printf("Error loading tournament: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Tournament not found"})
+		return
+	}
+
+	// Matches whose battles expired or timed out would otherwise stay active forever
+	if resolved, err := resolveStaleMatches(tournament); err != nil {
+		log.Printf("Error resolving stale matches in tournament %s: %v", tournamentId, err)
+	} else {
+		tournament = resolved
+	}
+
+	writeTournament(w, tournament)
+}
+
+// RegisterTournamentHandler enters the user in a tournament with the team they'll
+// play every match with
+func RegisterTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Authentication required"})
+		return
+	}
+
+	// Expected format: /tournaments/{tournamentId}/register
+	tournamentId, _ := tournamentPath(r.URL.Path)
+	if tournamentId == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Tournament ID required"})
+		return
+	}
+
+	var req ChallengeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("Error decoding request body: %v", err)
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Invalid request body"})
+		return
+	}
+
+	tournament, err := getTournamentStore().Load(tournamentId)
+	if err != nil {
+		log.Printf("Error loading tournament: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Tournament not found"})
+		return
+	}
+
+	if tournament.Status != "registration" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Registration is closed"})
+		return
+	}
+	if tournament.entrant(user.Sub) != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "You are already registered"})
+		return
+	}
+	if len(tournament.Entrants) >= tournament.MaxEntrants {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Tournament is full"})
+		return
+	}
+
+	team, status, err := fetchChallengeTeam(req)
+	if err != nil {
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: err.Error()})
+		return
+	}
+
+	now := time.Now()
+	tournament.Entrants = append(tournament.Entrants, TournamentEntrant{
+		UserId:       user.Sub,
+		Username:     user.Username,
+		Team:         team,
+		RegisteredAt: now.Format(time.RFC3339),
+	})
+	tournament.UpdatedAt = now.Format(time.RFC3339)
+
+	if err := getTournamentStore().Save(tournament); err != nil {
+		writeTournamentSaveError(w, err)
+		return
+	}
+
+	log.Printf("User %s registered for tournament %s with: %s", user.Username, tournamentId, teamNames(team))
+	writeTournament(w, tournament)
+}
+
+// StartTournamentHandler closes registration, generates the pairings and creates the
+// first round's battles. Only the organizer can start a tournament.
+func StartTournamentHandler(w http.ResponseWriter, r *http.Request) {
+	log.Printf("Request received: %s %s from %s", r.Method, r.URL.Path, r.RemoteAddr)
+
+	// Set CORS headers
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Method not allowed"})
+		return
+	}
+
+	// Get the user from context
+	user, ok := r.Context().Value(middleware.CognitoUserContextKey).(middleware.CognitoUser)
+	if !ok {
+		log.Printf("No user found in context")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Authentication required"})
+		return
+	}
+
+	// Expected format: /tournaments/{tournamentId}/start
+	tournamentId, _ := tournamentPath(r.URL.Path)
+	if tournamentId == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Tournament ID required"})
+		return
+	}
+
+	tournament, err := getTournamentStore().Load(tournamentId)
+	if err != nil || tournament.OwnerUserId != user.Sub {
+		log.Printf("Error loading tournament: %v", err)
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Tournament not found"})
+		return
+	}
+
+	if tournament.Status != "registration" {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Tournament has already started"})
+		return
+	}
+	if len(tournament.Entrants) < 2 {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "At least 2 entrants are required"})
+		return
+	}
+
+	now := time.Now()
+	tournament.seed(newTeamRNG(newBattleSeed()))
+	tournament.Status = "active"
+	battles := tournament.startRound(1, now)
+	tournament.UpdatedAt = now.Format(time.RFC3339)
+
+	if err := getTournamentStore().Save(tournament); err != nil {
+		writeTournamentSaveError(w, err)
+		return
+	}
+
+	if err := saveTournamentBattles(battles); err != nil {
+		log.Printf("Error creating tournament battles: %v", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(TournamentResponse{Error: "Failed to create battles"})
+		return
+	}
+
+	log.Printf("User %s started tournament %s with %d entrants", user.Username, tournamentId, len(tournament.Entrants))
+	writeTournament(w, tournament)
+}
+
+// memoryTournamentStore keeps tournaments in memory. Tournaments are lost on restart.
+type memoryTournamentStore struct {
+	tournaments map[string]memoryTournamentEntry
+	mutex       *sync.RWMutex
+}
+
+type memoryTournamentEntry struct {
+	data    []byte
+	version int
+}
+
+func newMemoryTournamentStore() *memoryTournamentStore {
+	return &memoryTournamentStore{
+		tournaments: make(map[string]memoryTournamentEntry),
+		mutex:       &sync.RWMutex{},
+	}
+}
+
+func (s *memoryTournamentStore) Save(tournament *Tournament) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if existing, exists := s.tournaments[tournament.TournamentId]; exists && existing.version != tournament.Version {
+		return ErrTournamentConflict
+	}
+
+	tournament.Version++
+	data, err := json.Marshal(tournament)
+	if err != nil {
+		tournament.Version--
+		return fmt.Errorf("failed to encode tournament: %w", err)
+	}
+
+	s.tournaments[tournament.TournamentId] = memoryTournamentEntry{data: data, version: tournament.Version}
+	return nil
+}
+
+func (s *memoryTournamentStore) Load(tournamentId string) (*Tournament, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entry, exists := s.tournaments[tournamentId]
+	if !exists {
+		return nil, ErrTournamentNotFound
+	}
+
+	var tournament Tournament
+	if err := json.Unmarshal(entry.data, &tournament); err != nil {
+		return nil, fmt.Errorf("failed to decode tournament: %w", err)
+	}
+	return &tournament, nil
+}
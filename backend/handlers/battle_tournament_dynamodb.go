@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// dynamoTournamentStore persists tournaments in DynamoDB. Unlike battles they
+// don't expire, so finished brackets stay available.
+type dynamoTournamentStore struct {
+	client    *dynamodb.Client
+	tableName string
+}
+
+// tournamentItem is the DynamoDB representation of a tournament
+type tournamentItem struct {
+	TournamentId string `dynamodbav:"tournamentId"`
+	OwnerUserId  string `dynamodbav:"ownerUserId"`
+	Data         string `dynamodbav:"data"`    // JSON encoded Tournament
+	Version      int    `dynamodbav:"version"` // Matches Tournament.Version, used for conditional writes
+}
+
+func newDynamoTournamentStore(tableName string) (*dynamoTournamentStore, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &dynamoTournamentStore{
+		client:    dynamodb.NewFromConfig(cfg),
+		tableName: tableName,
+	}, nil
+}
+
+func (s *dynamoTournamentStore) Save(tournament *Tournament) error {
+	loadedVersion := tournament.Version
+	tournament.Version++
+
+	data, err := json.Marshal(tournament)
+	if err != nil {
+		tournament.Version = loadedVersion
+		return fmt.Errorf("failed to encode tournament: %w", err)
+	}
+
+	item, err := attributevalue.MarshalMap(tournamentItem{
+		TournamentId: tournament.TournamentId,
+		OwnerUserId:  tournament.OwnerUserId,
+		Data:         string(data),
+		Version:      tournament.Version,
+	})
+	if err != nil {
+		tournament.Version = loadedVersion
+		return fmt.Errorf("failed to marshal tournament item: %w", err)
+	}
+
+	// Only overwrite the version this request loaded, so results finishing together can't clobber each other
+	_, err = s.client.PutItem(context.TODO(), &dynamodb.PutItemInput{
+		TableName:           &s.tableName,
+		Item:                item,
+		ConditionExpression: stringPtr("attribute_not_exists(tournamentId) OR version = :version"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":version": &types.AttributeValueMemberN{Value: strconv.Itoa(loadedVersion)},
+		},
+	})
+	if err != nil {
+		tournament.Version = loadedVersion
+		var conditionFailed *types.ConditionalCheckFailedException
+		if errors.As(err, &conditionFailed) {
+			return ErrTournamentConflict
+		}
+		return fmt.Errorf("failed to save tournament: %w", err)
+	}
+	return nil
+}
+
+func (s *dynamoTournamentStore) Load(tournamentId string) (*Tournament, error) {
+	result, err := s.client.GetItem(context.TODO(), &dynamodb.GetItemInput{
+		TableName: &s.tableName,
+		Key: map[string]types.AttributeValue{
+			"tournamentId": &types.AttributeValueMemberS{Value: tournamentId},
+		},
+		ConsistentRead: boolPtr(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load tournament: %w", err)
+	}
+	if result.Item == nil {
+		return nil, ErrTournamentNotFound
+	}
+
+	var item tournamentItem
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal tournament item: %w", err)
+	}
+
+	var tournament Tournament
+	if err := json.Unmarshal([]byte(item.Data), &tournament); err != nil {
+		return nil, fmt.Errorf("failed to decode tournament: %w", err)
+	}
+	return &tournament, nil
+}
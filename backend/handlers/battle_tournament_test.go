@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func testTournament(format string, entrants int) *Tournament {
+	tournament := &Tournament{TournamentId: "tournament", Format: format, Status: "active"}
+	for i := 1; i <= entrants; i++ {
+		tournament.Entrants = append(tournament.Entrants, TournamentEntrant{
+			UserId:   fmt.Sprintf("user-%d", i),
+			Username: fmt.Sprintf("trainer-%d", i),
+			Team:     testAIBattle().PlayerTeam,
+		})
+	}
+	tournament.seed(newTeamRNG(1))
+	return tournament
+}
+
+// playRound finishes every active match in the current round, the player side winning
+func playRound(t *testing.T, tournament *Tournament, battles []*BattleState) []*BattleState {
+	t.Helper()
+	var next []*BattleState
+	for _, battle := range battles {
+		found, started := tournament.recordResult(battle.BattleId, battle.UserId, time.Now())
+		if !found {
+			t.Fatalf("no active match for battle %s", battle.BattleId)
+		}
+		next = append(next, started...)
+	}
+	return next
+}
+
+func TestSingleEliminationTournament(t *testing.T) {
+	tournament := testTournament(TournamentSingleElimination, 5)
+	if tournament.Rounds != 3 {
+		t.Fatalf("Rounds = %d, want 3", tournament.Rounds)
+	}
+
+	battles := tournament.startRound(1, time.Now())
+	byes := 0
+	for _, match := range tournament.Matches {
+		if match.Status == "bye" {
+			byes++
+		}
+	}
+	if len(battles) != 1 || byes != 3 {
+		t.Fatalf("round 1 has %d battles and %d byes, want 1 and 3", len(battles), byes)
+	}
+	if battles[0].TournamentId != "tournament" || !battles[0].isPvP() || battles[0].BattleStatus != "active" {
+		t.Errorf("match battle = %+v, want an active PvP tournament battle", battles[0])
+	}
+
+	for round := 2; round <= 3; round++ {
+		battles = playRound(t, tournament, battles)
+		if tournament.CurrentRound != round {
+			t.Fatalf("CurrentRound = %d, want %d", tournament.CurrentRound, round)
+		}
+		if want := 1 << (3 - round); len(battles) != want {
+			t.Fatalf("round %d has %d battles, want %d", round, len(battles), want)
+		}
+	}
+
+	final := battles[0]
+	if battles = playRound(t, tournament, battles); len(battles) != 0 {
+		t.Errorf("started %d battles after the final", len(battles))
+	}
+	if tournament.Status != "finished" || tournament.WinnerUserId != final.UserId {
+		t.Errorf("status = %s, winner = %s, want finished and %s", tournament.Status, tournament.WinnerUserId, final.UserId)
+	}
+
+	standings := tournament.standings()
+	eliminated := 0
+	for _, standing := range standings {
+		if standing.Eliminated {
+			eliminated++
+		}
+	}
+	if standings[0].UserId != final.UserId || eliminated != 4 {
+		t.Errorf("standings = %+v, want %s first and 4 eliminated", standings, final.UserId)
+	}
+}
+
+func TestRoundRobinTournament(t *testing.T) {
+	tournament := testTournament(TournamentRoundRobin, 3)
+	if tournament.Rounds != 3 {
+		t.Fatalf("Rounds = %d, want 3", tournament.Rounds)
+	}
+
+	pairs := make(map[string]bool)
+	for _, match := range tournament.Matches {
+		if match.OpponentUserId == "" {
+			continue
+		}
+		pair := match.PlayerUserId + "-" + match.OpponentUserId
+		if match.OpponentUserId < match.PlayerUserId {
+			pair = match.OpponentUserId + "-" + match.PlayerUserId
+		}
+		if pairs[pair] {
+			t.Errorf("%s play each other twice", pair)
+		}
+		pairs[pair] = true
+	}
+	if len(pairs) != 3 {
+		t.Errorf("%d pairings, want every entrant to meet once", len(pairs))
+	}
+
+	battles := tournament.startRound(1, time.Now())
+	for round := 1; round <= 3; round++ {
+		if len(battles) != 1 {
+			t.Fatalf("round %d has %d battles, want 1", round, len(battles))
+		}
+		battles = playRound(t, tournament, battles)
+	}
+
+	if tournament.Status != "finished" {
+		t.Fatalf("status = %s, want finished", tournament.Status)
+	}
+	for _, standing := range tournament.standings() {
+		if standing.Wins+standing.Losses != 2 || standing.Byes != 1 {
+			t.Errorf("standing = %+v, want 2 matches and 1 bye", standing)
+		}
+	}
+}
+
+func TestRecordTournamentResult(t *testing.T) {
+	tournament := testTournament(TournamentSingleElimination, 2)
+	tournament.TournamentId = "record-result"
+	battles := tournament.startRound(1, time.Now())
+	if err := getTournamentStore().Save(tournament); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	battle := battles[0]
+	battle.BattleStatus = "lost"
+	battle.WinnerUserId = battle.OpponentUserId
+
+	// Recording the same battle twice only counts it once
+	for i := 0; i < 2; i++ {
+		if err := recordTournamentResult(battle); err != nil {
+			t.Fatalf("recordTournamentResult() error = %v", err)
+		}
+	}
+
+	saved, err := getTournamentStore().Load("record-result")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if saved.Status != "finished" || saved.WinnerUserId != battle.OpponentUserId {
+		t.Errorf("status = %s, winner = %s, want finished and %s", saved.Status, saved.WinnerUserId, battle.OpponentUserId)
+	}
+	if saved.Version != 2 {
+		t.Errorf("Version = %d, want 2", saved.Version)
+	}
+}
+
+func TestResolveStaleMatches(t *testing.T) {
+	tournament := testTournament(TournamentSingleElimination, 4)
+	tournament.TournamentId = "stale-matches"
+	battles := tournament.startRound(1, time.Now())
+	if err := getTournamentStore().Save(tournament); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// The first match is still being played, the second's battle expired
+	live, expired := battles[0], battles[1]
+	if err := saveBattleState(live); err != nil {
+		t.Fatalf("saveBattleState() error = %v", err)
+	}
+
+	resolved, err := resolveStaleMatches(tournament)
+	if err != nil {
+		t.Fatalf("resolveStaleMatches() error = %v", err)
+	}
+	for _, match := range resolved.Matches {
+		switch match.BattleId {
+		case live.BattleId:
+			if match.Status != "active" {
+				t.Errorf("live match status = %s, want active", match.Status)
+			}
+		case expired.BattleId:
+			if match.Status != "finished" || match.EndReason != "expired" || match.WinnerUserId != expired.UserId {
+				t.Errorf("expired match = %+v, want an expired win for %s", match, expired.UserId)
+			}
+		}
+	}
+
+	saved, err := getTournamentStore().Load("stale-matches")
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if saved.Version != resolved.Version || saved.CurrentRound != 1 {
+		t.Errorf("saved version %d round %d, want version %d still in round 1", saved.Version, saved.CurrentRound, resolved.Version)
+	}
+
+	// Resolving again changes nothing
+	again, err := resolveStaleMatches(saved)
+	if err != nil {
+		t.Fatalf("resolveStaleMatches() error = %v", err)
+	}
+	if again.Version != saved.Version {
+		t.Errorf("Version = %d after resolving again, want %d", again.Version, saved.Version)
+	}
+}
+
+func TestLargestTournamentFitsInOneItem(t *testing.T) {
+	// Every optional field set, so this is bigger than any team PokeAPI can produce
+	stats := PokemonStats{HP: 714, Attack: 614, Defense: 614, SpecialAttack: 614, SpecialDefense: 614, Speed: 614}
+	move := PokemonMove{
+		Name: "parabolic-charge", Power: 250, Type: "electric", PP: 64, CurrentPP: 64, Accuracy: 100, DamageClass: "special",
+		Ailment: "paralysis", AilmentChance: 100, StatChanges: []StatChange{{Stat: "special-attack", Change: -2}, {Stat: "special-defense", Change: -1}},
+		StatChance: 100, StatTarget: "target", Priority: 1, Drain: 50, Healing: 50, MinHits: 2, MaxHits: 5, FlinchChance: 30, CritRate: 1,
+	}
+	pokemon := BattlePokemon{
+		PokemonId: 10000, Name: "necrozma-dawn-wings", CurrentHP: 714, MaxHP: 714, Types: []string{"psychic", "ghost"},
+		SpriteUrl: "https://raw.githubusercontent.com/PokeAPI/sprites/master/sprites/pokemon/other/official-artwork/10000.png",
+		Moves:     []PokemonMove{move, move, move, move}, Stats: stats, BaseStats: stats, Level: MaxLevel, IVs: stats, EVs: stats,
+		Nature: "adamant", Ability: "prism-armor", Item: "leftovers",
+	}
+
+	tournament := &Tournament{TournamentId: fmt.Sprintf("us-east-1:%036d_1700000000_ffffffff", 0), Name: fmt.Sprintf("%0128d", 0), Format: TournamentRoundRobin}
+	for i := 0; i < MaxTournamentSize; i++ {
+		tournament.Entrants = append(tournament.Entrants, TournamentEntrant{
+			UserId:   fmt.Sprintf("us-east-1:%036d", i),
+			Username: fmt.Sprintf("%0128d", i),
+			Team:     []BattlePokemon{pokemon, pokemon, pokemon, pokemon, pokemon, pokemon},
+		})
+	}
+	tournament.seed(newTeamRNG(1))
+	for i := range tournament.Matches {
+		match := &tournament.Matches[i]
+		match.BattleId = fmt.Sprintf("%s_r%d_m%d", tournament.TournamentId, match.Round, i)
+		match.WinnerUserId = match.PlayerUserId
+		match.Status = "finished"
+	}
+
+	data, err := json.Marshal(tournament)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	if len(data) > MaxTournamentItemBytes {
+		t.Errorf("a full tournament is %d bytes, over the %d byte item limit", len(data), MaxTournamentItemBytes)
+	}
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import "testing"
+
+func TestTypeEffectiveness(t *testing.T) {
+	tests := []struct {
+		name          string
+		moveType      string
+		defenderTypes []string
+		want          float64
+	}{
+		{
+			name:          "neutral matchup",
+			moveType:      "normal",
+			defenderTypes: []string{"water"},
+			want:          1,
+		},
+		{
+			name:          "super effective",
+			moveType:      "water",
+			defenderTypes: []string{"fire"},
+			want:          2,
+		},
+		{
+			name:          "not very effective",
+			moveType:      "water",
+			defenderTypes: []string{"grass"},
+			want:          0.5,
+		},
+		{
+			name:          "immunity",
+			moveType:      "ground",
+			defenderTypes: []string{"flying"},
+			want:          0,
+		},
+		{
+			name:          "dual type double weakness",
+			moveType:      "ice",
+			defenderTypes: []string{"dragon", "flying"},
+			want:          4,
+		},
+		{
+			name:          "dual type cancels out",
+			moveType:      "fire",
+			defenderTypes: []string{"water", "grass"},
+			want:          1,
+		},
+		{
+			name:          "dual type immunity wins",
+			moveType:      "electric",
+			defenderTypes: []string{"water", "ground"},
+			want:          0,
+		},
+		{
+			name:          "unknown move type",
+			moveType:      "shadow",
+			defenderTypes: []string{"normal"},
+			want:          1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := typeEffectiveness(tt.moveType, tt.defenderTypes)
+			if result != tt.want {
+				t.Errorf("typeEffectiveness() = %v, want %v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestEffectivenessMessage(t *testing.T) {
+	tests := []struct {
+		name       string
+		multiplier float64
+		want       string
+	}{
+		{name: "no effect", multiplier: 0, want: "It had no effect..."},
+		{name: "not very effective", multiplier: 0.25, want: "It's not very effective..."},
+		{name: "neutral", multiplier: 1, want: ""},
+		{name: "super effective", multiplier: 4, want: "It's super effective!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := effectivenessMessage(tt.multiplier)
+			if result != tt.want {
+				t.Errorf("effectivenessMessage() = %q, want %q", result, tt.want)
+			}
+		})
+	}
+}
@@ -10,10 +10,15 @@ import (
 )
 
 func main() {
+	// Fail now rather than on the first battle if battle storage is misconfigured
+	handlers.InitStorage()
+
 	// Public endpoints (no auth required)
 	http.HandleFunc("/", handlers.HelloHandler)
 	http.HandleFunc("/login", handlers.LoginHandler)
 	http.HandleFunc("/register", handlers.RegisterHandler)
+	http.HandleFunc("/spectate/", handlers.SpectateBattleHandler) // Authorized by the battle's spectator token
+	http.HandleFunc("/battle-events/", handlers.StreamBattleEventsHandler) // Authorized by a player's stream token
 	
 	// Protected endpoints (Cognito auth required)
 	http.HandleFunc("/bedrock", middleware.CognitoAuthMiddleware(handlers.BedrockHandler))
@@ -25,9 +30,36 @@ func main() {
 	http.HandleFunc("/delete-pokemon/", middleware.CognitoAuthMiddleware(handlers.DeletePokemonHandler))
 	http.HandleFunc("/pokify", middleware.CognitoAuthMiddleware(handlers.PokifyHandler))
 	http.HandleFunc("/start-battle", middleware.CognitoAuthMiddleware(handlers.StartBattleHandler))
+	http.HandleFunc("/battle-challenge", middleware.CognitoAuthMiddleware(handlers.CreateChallengeHandler))
+	http.HandleFunc("/battle-replay", middleware.CognitoAuthMiddleware(handlers.ImportReplayHandler))
+	http.HandleFunc("/battle-simulate", middleware.CognitoAuthMiddleware(handlers.SimulateBattlesHandler))
+	http.HandleFunc("/battle-history", middleware.CognitoAuthMiddleware(handlers.BattleHistoryHandler))
+	http.HandleFunc("/battle-stats", middleware.CognitoAuthMiddleware(handlers.BattleStatsHandler))
+	http.HandleFunc("/ladder", middleware.CognitoAuthMiddleware(handlers.LeaderboardHandler))
+	http.HandleFunc("/ladder/history", middleware.CognitoAuthMiddleware(handlers.RatingHistoryHandler))
+	http.HandleFunc("/tournaments", middleware.CognitoAuthMiddleware(handlers.CreateTournamentHandler))
+	http.HandleFunc("/tournaments/", middleware.CognitoAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/register") {
+			handlers.RegisterTournamentHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/start") {
+			handlers.StartTournamentHandler(w, r)
+		} else {
+			handlers.TournamentHandler(w, r)
+		}
+	}))
 	http.HandleFunc("/battle/", middleware.CognitoAuthMiddleware(func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasSuffix(r.URL.Path, "/move") {
 			handlers.MakeMoveHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/accept") {
+			handlers.AcceptChallengeHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/stream-token") {
+			handlers.CreateStreamTokenHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/events") {
+			handlers.BattleEventsHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/spectate") {
+			handlers.CreateSpectatorLinkHandler(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/replay") {
+			handlers.ExportReplayHandler(w, r)
 		} else {
 			handlers.GetBattleHandler(w, r)
 		}
@@ -46,6 +78,25 @@ func main() {
 	log.Println("  POST /start-battle - Start a new Pokemon battle (authenticated)")
 	log.Println("  GET /battle/{battleId} - Get battle state (authenticated)")
 	log.Println("  POST /battle/{battleId}/move - Make a move in battle (authenticated)")
+	log.Println("  GET /battle/{battleId}/events - Stream live battle events (authenticated)")
+	log.Println("  POST /battle/{battleId}/stream-token - Create a short-lived token for streaming from a browser (authenticated)")
+	log.Println("  GET /battle-events/{battleId}?token={token} - Stream live battle events with a stream token")
+	log.Println("  POST /battle/{battleId}/spectate - Create a read-only spectator link (authenticated)")
+	log.Println("  GET /spectate/{battleId}?token={token} - Watch a battle with a spectator token")
+	log.Println("  GET /spectate/{battleId}/events?token={token} - Stream a battle with a spectator token")
+	log.Println("  GET /battle/{battleId}/replay - Export a finished battle's replay (authenticated)")
+	log.Println("  POST /battle-replay - Re-simulate an uploaded replay (authenticated)")
+	log.Println("  POST /battle-simulate - Simulate many AI-vs-AI battles between two teams (authenticated)")
+	log.Println("  GET /battle-history?limit={limit}&cursor={cursor} - List finished battles (authenticated)")
+	log.Println("  GET /battle-stats - Win rates per Pokemon and opponent type (authenticated)")
+	log.Println("  GET /ladder?ladder={ladder}&limit={limit}&cursor={cursor} - Elo leaderboard (authenticated)")
+	log.Println("  GET /ladder/history?ladder={ladder} - Your rating and rating history (authenticated)")
+	log.Println("  POST /battle-challenge - Create a player-vs-player challenge (authenticated)")
+	log.Println("  POST /battle/{battleId}/accept - Accept a player-vs-player challenge (authenticated)")
+	log.Println("  POST /tournaments - Create a single-elimination or round-robin tournament (authenticated)")
+	log.Println("  POST /tournaments/{tournamentId}/register - Register for a tournament with a team (authenticated)")
+	log.Println("  POST /tournaments/{tournamentId}/start - Pair entrants and create the first round's battles (authenticated)")
+	log.Println("  GET /tournaments/{tournamentId} - Get a tournament's bracket and standings (authenticated)")
 	if err := http.ListenAndServe(":8181", nil); err != nil {
 		log.Fatal(err)
 	}
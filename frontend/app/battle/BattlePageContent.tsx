@@ -14,6 +14,33 @@ interface BattlePokemon {
   spriteUrl: string;
   moves: PokemonMove[];
   stats: PokemonStats;
+  baseStats: PokemonStats;
+  level: number;
+  ivs: PokemonStats;
+  evs: PokemonStats;
+  nature: string;
+  status?: string;
+  sleepTurns?: number;
+  statStages: StatStages;
+  ability?: string;
+  item?: string;
+  itemConsumed?: boolean;
+  flinched?: boolean;
+}
+
+interface StatStages {
+  attack: number;
+  defense: number;
+  specialAttack: number;
+  specialDefense: number;
+  speed: number;
+  accuracy: number;
+  evasion: number;
+}
+
+interface StatChange {
+  stat: string;
+  change: number;
 }
 
 interface PokemonMove {
@@ -22,12 +49,28 @@ interface PokemonMove {
   type: string;
   pp: number;
   currentPp: number;
+  accuracy: number;
+  damageClass: string;
+  ailment?: string;
+  ailmentChance?: number;
+  statChanges?: StatChange[];
+  statChance?: number;
+  statTarget?: string;
+  priority?: number;
+  drain?: number;
+  healing?: number;
+  minHits?: number;
+  maxHits?: number;
+  flinchChance?: number;
+  critRate?: number;
 }
 
 interface PokemonStats {
   hp: number;
   attack: number;
   defense: number;
+  specialAttack: number;
+  specialDefense: number;
   speed: number;
 }
 
@@ -37,25 +80,75 @@ interface TurnAction {
   action: string;
   moveName: string;
   damage: number;
+  effectiveness: number;
+  effectivenessMessage?: string;
+  missed: boolean;
+  criticalHit: boolean;
+  stab: boolean;
+  statusInflicted?: string;
+  statChanges?: StatChange[];
+  recoil?: number;
+  hits?: number;
+  drained?: number;
+  healed?: number;
   message: string;
   timestamp: string;
 }
 
+interface FieldState {
+  weather?: string;
+  weatherTurns?: number;
+  terrain?: string;
+  terrainTurns?: number;
+}
+
 interface BattleState {
   battleId: string;
   userId: string;
-  playerPokemon: BattlePokemon;
-  computerPokemon: BattlePokemon;
+  mode: string;
+  opponentUserId?: string;
+  playerName?: string;
+  opponentName?: string;
+  playerTeam: BattlePokemon[];
+  computerTeam: BattlePokemon[];
+  playerActive: number;
+  computerActive: number;
+  field: FieldState;
+  difficulty: string;
+  opponentStrategy: string;
   currentTurn: string;
   battleStatus: string;
+  winnerUserId?: string;
+  endReason?: string;
+  forfeitedBy?: string;
+  turnDeadline?: string;
+  timeoutStrikes?: Record<string, number>;
+  pendingChoices?: Record<string, { action: string; moveName?: string; switchTo?: number }>;
+  forcedSwitches?: string[];
+  version: number;
+  spectatorToken?: string;
+  seed?: number;
+  customSeed?: boolean;
+  legalActions?: { action: string; moveName?: string; switchTo?: number }[];
+  tournamentId?: string;
   createdAt: string;
   updatedAt: string;
   turnHistory: TurnAction[];
 }
 
+interface TurnOrder {
+  order: string[];
+  reason: string;
+  priority: Record<string, number>;
+  speed: Record<string, number>;
+}
+
 interface TurnResult {
   playerAction?: TurnAction;
   computerAction?: TurnAction;
+  statusEvents?: TurnAction[];
+  switchRequired?: boolean;
+  turnOrder?: TurnOrder;
   battleEnded: boolean;
   winner?: string;
 }
@@ -327,23 +420,23 @@ export default function BattlePageContent() {
                   <h3 className="font-semibold text-blue-900 mb-2">Your Pokemon</h3>
                   <div className="text-center">
                     <img
-                      src={battleState.playerPokemon.spriteUrl}
-                      alt={battleState.playerPokemon.name}
+                      src={battleState.playerTeam[battleState.playerActive].spriteUrl}
+                      alt={battleState.playerTeam[battleState.playerActive].name}
                       className="w-24 h-24 mx-auto mb-2"
                     />
-                    <h4 className="font-bold text-lg capitalize">{battleState.playerPokemon.name}</h4>
+                    <h4 className="font-bold text-lg capitalize">{battleState.playerTeam[battleState.playerActive].name}</h4>
                     <div className="mt-2">
                       <div className="flex justify-between text-sm mb-1">
                         <span>HP</span>
-                        <span>{battleState.playerPokemon.currentHp}/{battleState.playerPokemon.maxHp}</span>
+                        <span>{battleState.playerTeam[battleState.playerActive].currentHp}/{battleState.playerTeam[battleState.playerActive].maxHp}</span>
                       </div>
                       <div className="w-full bg-gray-200 rounded-full h-2">
                         <div
                           className={`h-2 rounded-full transition-all duration-300 ${getHpColor(
-                            getHpPercentage(battleState.playerPokemon.currentHp, battleState.playerPokemon.maxHp)
+                            getHpPercentage(battleState.playerTeam[battleState.playerActive].currentHp, battleState.playerTeam[battleState.playerActive].maxHp)
                           )}`}
                           style={{
-                            width: `${getHpPercentage(battleState.playerPokemon.currentHp, battleState.playerPokemon.maxHp)}%`
+                            width: `${getHpPercentage(battleState.playerTeam[battleState.playerActive].currentHp, battleState.playerTeam[battleState.playerActive].maxHp)}%`
                           }}
                         />
                       </div>
@@ -356,23 +449,23 @@ export default function BattlePageContent() {
                   <h3 className="font-semibold text-red-900 mb-2">Opponent Pokemon</h3>
                   <div className="text-center">
                     <img
-                      src={battleState.computerPokemon.spriteUrl}
-                      alt={battleState.computerPokemon.name}
+                      src={battleState.computerTeam[battleState.computerActive].spriteUrl}
+                      alt={battleState.computerTeam[battleState.computerActive].name}
                       className="w-24 h-24 mx-auto mb-2"
                     />
-                    <h4 className="font-bold text-lg capitalize">{battleState.computerPokemon.name}</h4>
+                    <h4 className="font-bold text-lg capitalize">{battleState.computerTeam[battleState.computerActive].name}</h4>
                     <div className="mt-2">
                       <div className="flex justify-between text-sm mb-1">
                         <span>HP</span>
-                        <span>{battleState.computerPokemon.currentHp}/{battleState.computerPokemon.maxHp}</span>
+                        <span>{battleState.computerTeam[battleState.computerActive].currentHp}/{battleState.computerTeam[battleState.computerActive].maxHp}</span>
                       </div>
                       <div className="w-full bg-gray-200 rounded-full h-2">
                         <div
                           className={`h-2 rounded-full transition-all duration-300 ${getHpColor(
-                            getHpPercentage(battleState.computerPokemon.currentHp, battleState.computerPokemon.maxHp)
+                            getHpPercentage(battleState.computerTeam[battleState.computerActive].currentHp, battleState.computerTeam[battleState.computerActive].maxHp)
                           )}`}
                           style={{
-                            width: `${getHpPercentage(battleState.computerPokemon.currentHp, battleState.computerPokemon.maxHp)}%`
+                            width: `${getHpPercentage(battleState.computerTeam[battleState.computerActive].currentHp, battleState.computerTeam[battleState.computerActive].maxHp)}%`
                           }}
                         />
                       </div>
@@ -401,7 +494,7 @@ export default function BattlePageContent() {
                 <div className="bg-white border rounded-lg p-4">
                   <h4 className="font-semibold mb-3">Choose your move:</h4>
                   <div className="grid grid-cols-1 sm:grid-cols-2 gap-3">
-                    {battleState.playerPokemon.moves.map((move, index) => (
+                    {battleState.playerTeam[battleState.playerActive].moves.map((move, index) => (
                       <button
                         key={index}
                         onClick={() => makeMove(move.name)}
@@ -427,6 +520,14 @@ export default function BattlePageContent() {
                       </button>
                     ))}
                   </div>
+                  {battleState.legalActions?.some((action) => action.moveName === 'struggle') && (
+                    <button
+                      onClick={() => makeMove('struggle')}
+                      className="mt-3 w-full p-3 rounded-md border border-gray-300 bg-white hover:bg-gray-50 font-medium"
+                    >
+                      Struggle (no PP left)
+                    </button>
+                  )}
                 </div>
               )}
 
@@ -449,7 +550,7 @@ export default function BattlePageContent() {
                     <div className="text-6xl mb-4">🎉</div>
                     <h2 className="text-3xl font-bold text-green-600 mb-2">Victory!</h2>
                     <p className="text-gray-700">
-                      Your {battleState.playerPokemon.name} defeated {battleState.computerPokemon.name}!
+                      Your {battleState.playerTeam[battleState.playerActive].name} defeated {battleState.computerTeam[battleState.computerActive].name}!
                     </p>
                   </div>
                 ) : (
@@ -457,7 +558,7 @@ export default function BattlePageContent() {
                     <div className="text-6xl mb-4">😞</div>
                     <h2 className="text-3xl font-bold text-red-600 mb-2">Defeat!</h2>
                     <p className="text-gray-700">
-                      {battleState.computerPokemon.name} defeated your {battleState.playerPokemon.name}!
+                      {battleState.computerTeam[battleState.computerActive].name} defeated your {battleState.playerTeam[battleState.playerActive].name}!
                     </p>
                   </div>
                 )}
@@ -8,6 +8,10 @@ export class SlowikPokeInfraStack extends cdk.Stack {
   public readonly userPool: cognito.UserPool;
   public readonly userPoolClient: cognito.UserPoolClient;
   public readonly pokemonTable: dynamodb.Table;
+  public readonly battlesTable: dynamodb.Table;
+  public readonly battleHistoryTable: dynamodb.Table;
+  public readonly ratingsTable: dynamodb.Table;
+  public readonly tournamentsTable: dynamodb.Table;
   public readonly bedrockRole: iam.Role;
 
   constructor(scope: Construct, id: string, props?: cdk.StackProps) {
@@ -126,6 +130,96 @@ export class SlowikPokeInfraStack extends cdk.Stack {
       description: "Pokemon entries table ARN",
     });
 
+    // Create DynamoDB table for battles in progress
+    this.battlesTable = new dynamodb.Table(this, "PokemonBattlesTable", {
+      tableName: "pokemon-battles",
+      partitionKey: {
+        name: "battleId",
+        type: dynamodb.AttributeType.STRING,
+      },
+      timeToLiveAttribute: "expiresAt",
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+      removalPolicy: cdk.RemovalPolicy.DESTROY, // For development
+      pointInTimeRecoverySpecification: { pointInTimeRecoveryEnabled: false },
+    });
+
+    new cdk.CfnOutput(this, "BattlesTableName", {
+      value: this.battlesTable.tableName,
+      description: "Pokemon battles table name",
+    });
+
+    // Create DynamoDB table for finished battle summaries, newest first per user
+    this.battleHistoryTable = new dynamodb.Table(this, "PokemonBattleHistoryTable", {
+      tableName: "pokemon-battle-history",
+      partitionKey: {
+        name: "userId",
+        type: dynamodb.AttributeType.STRING,
+      },
+      sortKey: {
+        name: "sortKey",
+        type: dynamodb.AttributeType.STRING,
+      },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+      removalPolicy: cdk.RemovalPolicy.DESTROY, // For development
+      pointInTimeRecoverySpecification: { pointInTimeRecoveryEnabled: false },
+    });
+
+    new cdk.CfnOutput(this, "BattleHistoryTableName", {
+      value: this.battleHistoryTable.tableName,
+      description: "Pokemon battle history table name",
+    });
+
+    // Create DynamoDB table for ladder ratings, one item per player per ladder
+    this.ratingsTable = new dynamodb.Table(this, "PokemonRatingsTable", {
+      tableName: "pokemon-ratings",
+      partitionKey: {
+        name: "ladder",
+        type: dynamodb.AttributeType.STRING,
+      },
+      sortKey: {
+        name: "userId",
+        type: dynamodb.AttributeType.STRING,
+      },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+      removalPolicy: cdk.RemovalPolicy.DESTROY, // For development
+      pointInTimeRecoverySpecification: { pointInTimeRecoveryEnabled: false },
+    });
+
+    // Add Global Secondary Index for leaderboards, highest rating first
+    this.ratingsTable.addGlobalSecondaryIndex({
+      indexName: "ladder-rating-index",
+      partitionKey: {
+        name: "ladder",
+        type: dynamodb.AttributeType.STRING,
+      },
+      sortKey: {
+        name: "rating",
+        type: dynamodb.AttributeType.NUMBER,
+      },
+    });
+
+    new cdk.CfnOutput(this, "RatingsTableName", {
+      value: this.ratingsTable.tableName,
+      description: "Pokemon ladder ratings table name",
+    });
+
+    // Create DynamoDB table for tournaments and their brackets
+    this.tournamentsTable = new dynamodb.Table(this, "PokemonTournamentsTable", {
+      tableName: "pokemon-tournaments",
+      partitionKey: {
+        name: "tournamentId",
+        type: dynamodb.AttributeType.STRING,
+      },
+      billingMode: dynamodb.BillingMode.PAY_PER_REQUEST,
+      removalPolicy: cdk.RemovalPolicy.DESTROY, // For development
+      pointInTimeRecoverySpecification: { pointInTimeRecoveryEnabled: false },
+    });
+
+    new cdk.CfnOutput(this, "TournamentsTableName", {
+      value: this.tournamentsTable.tableName,
+      description: "Pokemon tournaments table name",
+    });
+
     // Create IAM role for Bedrock on-demand access
     this.bedrockRole = new iam.Role(this, "BedrockExecutionRole", {
       roleName: "pokemon-bedrock-execution-role",